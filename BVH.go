@@ -3,6 +3,8 @@ package bvhtree
 import (
 	"math"
 	"sort"
+	"sync"
+	"sync/atomic"
 )
 
 const EPSILON = 1e-6
@@ -24,44 +26,77 @@ type IntersectionResult struct {
 	Triangle          Triangle
 	TriangleIndex     int
 	IntersectionPoint Point
+	Distance          float64 // Ray parameter t of the intersection point, the distance if rayDirection is normalized
+	U, V              float64 // Barycentric coordinates of the intersection point, weights of Triangle[1] and Triangle[2]
+	Normal            Point   // Unit geometric normal of the triangle, following the winding order a, b, c
+	FrontFace         bool    // True if the ray hit the side of the triangle the normal points to
+}
+
+// triangleHit holds the ray parameter and barycentric coordinates of a ray-triangle intersection
+type triangleHit struct {
+	t, u, v   float64
+	frontFace bool
 }
 
 // BVH represents a bounding volume hierarchy
 type BVH struct {
-	vertexArray         []float64
-	maxTrianglesPerNode int
-	bboxArray           []float64
-	bboxHelper          []float64
-	rootNode            *Node
-	nodesToSplit        []*Node
+	vertexArray          []float64
+	options              BuildOptions
+	triangleIntersection TriangleIntersection
+	bboxArray            []float64
+	bboxHelper           []float64
+	rootNode             *Node
+	duplicateReferences  bool         // Some triangles are referenced from more than one leaf, see SplitSpatialSAH
+	dynamic              *dynamicTree // Bookkeeping for Insert and Remove, nil until the first edit
+	flatMutex            sync.Mutex   // Serializes flattening when concurrent queries find flatNodes empty
+	flatNodes            atomic.Value // []flatNode used by the ray queries, patched by Insert and Remove and discarded by other changes
 }
 
 // NewBVH creates a new BVH from a list of triangles
 func NewBVHFromVertexArray(vertexArray []float64, maxTrianglesPerNode int) *BVH {
+	options := DefaultBuildOptions()
+	options.MaxTrianglesPerNode = maxTrianglesPerNode
+	return NewBVHFromVertexArrayWithOptions(vertexArray, options)
+}
+
+// NewBVHFromVertexArrayWithOptions creates a new BVH from a vertex array using the given build options
+func NewBVHFromVertexArrayWithOptions(vertexArray []float64, options BuildOptions) *BVH {
 	bvh := &BVH{
-		vertexArray:         vertexArray,
-		maxTrianglesPerNode: maxTrianglesPerNode,
+		vertexArray: vertexArray,
+		options:     options.normalized(),
 	}
 
-	bvh.bboxArray = bvh.CalcBoundingBoxes(vertexArray)
+	bvh.bboxArray = bvh.calcBoundingBoxesParallel(vertexArray, bvh.options.Parallelism)
 	bvh.bboxHelper = make([]float64, len(bvh.bboxArray))
 	copy(bvh.bboxHelper, bvh.bboxArray)
 
 	triangleCount := len(vertexArray) / 9
-	extents := bvh.CalcExtents(0, triangleCount, EPSILON)
+	extents := bvh.CalcExtents(0, triangleCount, bvh.options.Padding)
 	bvh.rootNode = NewBVHNode(extents[0], extents[1], 0, triangleCount, 0)
-	bvh.nodesToSplit = []*Node{bvh.rootNode}
-
-	for len(bvh.nodesToSplit) > 0 {
-		node := bvh.nodesToSplit[len(bvh.nodesToSplit)-1]
-		bvh.nodesToSplit = bvh.nodesToSplit[:len(bvh.nodesToSplit)-1]
-		bvh.SplitNode(node)
+	progress := newBuildProgress(triangleCount, bvh.options.Progress)
+	switch bvh.options.SplitStrategy {
+	case SplitLBVH:
+		bvh.buildLBVH(bvh.rootNode, progress)
+	case SplitSpatialSAH:
+		bvh.buildSBVH(bvh.rootNode, progress)
+	default:
+		bvh.splitNodes(bvh.rootNode, progress)
 	}
 
 	return bvh
 }
 
 func NewBVH(triangles []Triangle, maxTrianglesPerNode int) *BVH {
+	return NewBVHFromVertexArray(vertexArrayFromTriangles(triangles), maxTrianglesPerNode)
+}
+
+// NewBVHWithOptions creates a new BVH from a list of triangles using the given build options
+func NewBVHWithOptions(triangles []Triangle, options BuildOptions) *BVH {
+	return NewBVHFromVertexArrayWithOptions(vertexArrayFromTriangles(triangles), options)
+}
+
+// vertexArrayFromTriangles flattens a list of triangles into a vertex array of 9 values per triangle
+func vertexArrayFromTriangles(triangles []Triangle) []float64 {
 	vertexArray := make([]float64, len(triangles)*9)
 	for i, tri := range triangles {
 		vertexArray[i*9] = tri[0].X
@@ -76,43 +111,91 @@ func NewBVH(triangles []Triangle, maxTrianglesPerNode int) *BVH {
 		vertexArray[i*9+7] = tri[2].Y
 		vertexArray[i*9+8] = tri[2].Z
 	}
-
-	return NewBVHFromVertexArray(vertexArray, maxTrianglesPerNode)
+	return vertexArray
 }
 
 func (bvh *BVH) VertexArray() []float64 {
 	return bvh.vertexArray
 }
 
+// Triangle returns a copy of the triangle at the specified index of the vertex array
+func (bvh *BVH) Triangle(triIndex int) Triangle {
+	a := &Vector3{}
+	b := &Vector3{}
+	c := &Vector3{}
+	a.SetFromArray(bvh.vertexArray, triIndex*9)
+	b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+	c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+	return Triangle{a, b, c}
+}
+
+// newIntersectionResult creates the result for a ray hitting the triangle at triIndex.
+// The points of the result are taken from vectors, which may be nil to allocate them on the heap.
+func (bvh *BVH) newIntersectionResult(vectors *vectorPool, triIndex int, rayOrigin, rayDirection Point, hit triangleHit) IntersectionResult {
+	a := vectors.get()
+	b := vectors.get()
+	c := vectors.get()
+	a.SetFromArray(bvh.vertexArray, triIndex*9)
+	b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+	c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+	return IntersectionResult{
+		Triangle:          Triangle{a, b, c},
+		TriangleIndex:     triIndex,
+		IntersectionPoint: vectors.get().Copy(rayDirection).MultiplyScalar(hit.t).Add(rayOrigin),
+		Distance:          hit.t,
+		U:                 hit.u,
+		V:                 hit.v,
+		Normal:            calcTriangleNormalInto(vectors.get(), a, b, c),
+		FrontFace:         hit.frontFace,
+	}
+}
+
 // IntersectRay returns a list of all the triangles in the BVH which intersected a specific ray
 func (bvh *BVH) IntersectRay(rayOrigin, rayDirection Point, backfaceCulling bool) []IntersectionResult {
-	nodesToIntersect := []*Node{bvh.rootNode}
-	var trianglesInIntersectingNodes []int
-	var intersectingTriangles []IntersectionResult
+	return bvh.IntersectRayRange(rayOrigin, rayDirection, 0, math.Inf(1), backfaceCulling)
+}
 
-	invRayDirection := &Vector3{
-		X: 1.0 / rayDirection.X,
-		Y: 1.0 / rayDirection.Y,
-		Z: 1.0 / rayDirection.Z,
-	}
+// IntersectRayRange returns a list of all the triangles in the BVH which intersected a specific ray between the ray parameters tmin and tmax
+func (bvh *BVH) IntersectRayRange(rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) []IntersectionResult {
+	return bvh.intersectRay(NewQueryContext(), nil, rayOrigin, rayDirection, tmin, tmax, backfaceCulling, nil)
+}
+
+// intersectRay appends all intersections of the ray between tmin and tmax to intersectingTriangles,
+// using the buffers in ctx for the traversal and taking the points of the results from vectors
+func (bvh *BVH) intersectRay(ctx *QueryContext, vectors *vectorPool, rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool, intersectingTriangles []IntersectionResult) []IntersectionResult {
+	nodes := bvh.flatTree()
+	nodesToIntersect := append(ctx.nodes[:0], 0)
+	trianglesInIntersectingNodes := ctx.triangles[:0]
+
+	ray := newFlatRay(rayOrigin, rayDirection)
 
 	for len(nodesToIntersect) > 0 {
-		node := nodesToIntersect[len(nodesToIntersect)-1]
+		index := nodesToIntersect[len(nodesToIntersect)-1]
 		nodesToIntersect = nodesToIntersect[:len(nodesToIntersect)-1]
 
-		if IntersectNodeBox(rayOrigin, invRayDirection, node) {
-			if node.Node0 != nil {
-				nodesToIntersect = append(nodesToIntersect, node.Node0)
-			}
-			if node.Node1 != nil {
-				nodesToIntersect = append(nodesToIntersect, node.Node1)
-			}
-			for i := node.StartIndex; i < node.EndIndex; i++ {
-				trianglesInIntersectingNodes = append(trianglesInIntersectingNodes, int(bvh.bboxArray[i*7]))
-			}
+		node := &nodes[index]
+		if _, ok := intersectFlatNodeT(&ray, node, tmin, tmax); !ok {
+			continue
+		}
+
+		if !node.isLeaf() {
+			index0, index1 := node.children()
+			nodesToIntersect = append(nodesToIntersect, index0, index1)
+			continue
+		}
+		for i := node.offset; i < node.offset+node.count; i++ {
+			trianglesInIntersectingNodes = append(trianglesInIntersectingNodes, int(bvh.bboxArray[i*7]))
 		}
 	}
 
+	if bvh.duplicateReferences {
+		trianglesInIntersectingNodes = dedupeTriangles(trianglesInIntersectingNodes)
+	}
+
+	ctx.nodes = nodesToIntersect
+	ctx.triangles = trianglesInIntersectingNodes
+
 	a := &Vector3{}
 	b := &Vector3{}
 	c := &Vector3{}
@@ -124,12 +207,8 @@ func (bvh *BVH) IntersectRay(rayOrigin, rayDirection Point, backfaceCulling bool
 		b.SetFromArray(bvh.vertexArray, triIndex*9+3)
 		c.SetFromArray(bvh.vertexArray, triIndex*9+6)
 
-		if intersectionPoint := IntersectRayTriangle(a, b, c, rayOriginVec3, rayDirectionVec3, backfaceCulling); intersectionPoint != nil {
-			intersectingTriangles = append(intersectingTriangles, IntersectionResult{
-				Triangle:          Triangle{a, b, c},
-				TriangleIndex:     triIndex,
-				IntersectionPoint: intersectionPoint,
-			})
+		if hit, ok := bvh.intersectTriangle(a, b, c, rayOriginVec3, rayDirectionVec3, tmin, tmax, backfaceCulling); ok {
+			intersectingTriangles = append(intersectingTriangles, bvh.newIntersectionResult(vectors, triIndex, rayOriginVec3, rayDirectionVec3, hit))
 		}
 	}
 
@@ -141,8 +220,40 @@ func (bvh *BVH) IntersectRay(rayOrigin, rayDirection Point, backfaceCulling bool
 func (bvh *BVH) CalcBoundingBoxes(vertexArray []float64) []float64 {
 	triangleCount := len(vertexArray) / 9
 	bboxArray := make([]float64, triangleCount*7)
+	calcBoundingBoxesRange(vertexArray, bboxArray, 0, triangleCount)
+	return bboxArray
+}
+
+// calcBoundingBoxesParallel does the same as CalcBoundingBoxes but splits the triangles across the given number of goroutines
+func (bvh *BVH) calcBoundingBoxesParallel(vertexArray []float64, workers int) []float64 {
+	triangleCount := len(vertexArray) / 9
+	if workers <= 1 || triangleCount < workers {
+		return bvh.CalcBoundingBoxes(vertexArray)
+	}
+
+	bboxArray := make([]float64, triangleCount*7)
+	chunkSize := (triangleCount + workers - 1) / workers
 
-	for i := 0; i < triangleCount; i++ {
+	var wg sync.WaitGroup
+	for start := 0; start < triangleCount; start += chunkSize {
+		end := start + chunkSize
+		if end > triangleCount {
+			end = triangleCount
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			calcBoundingBoxesRange(vertexArray, bboxArray, start, end)
+		}(start, end)
+	}
+	wg.Wait()
+
+	return bboxArray
+}
+
+// calcBoundingBoxesRange writes the bounding boxes of the triangles in [startIndex, endIndex) to bboxArray
+func calcBoundingBoxesRange(vertexArray, bboxArray []float64, startIndex, endIndex int) {
+	for i := startIndex; i < endIndex; i++ {
 		p0x := vertexArray[i*9]
 		p0y := vertexArray[i*9+1]
 		p0z := vertexArray[i*9+2]
@@ -162,8 +273,6 @@ func (bvh *BVH) CalcBoundingBoxes(vertexArray []float64) []float64 {
 
 		SetBox(bboxArray, i, i, minX, minY, minZ, maxX, maxY, maxZ)
 	}
-
-	return bboxArray
 }
 
 // CalcExtents calculates the extents (i.e., the min and max coordinates) of a list of bounding boxes in the bboxArray.
@@ -195,12 +304,46 @@ func (bvh *BVH) CalcExtents(startIndex, endIndex int, expandBy float64) [2]Point
 	}
 }
 
-// SplitNode splits a node if it contains more elements than maxTrianglesPerNode
+// SplitNode splits a node into two children according to the BVH's split strategy
 func (bvh *BVH) SplitNode(node *Node) {
-	if node.ElementCount() <= bvh.maxTrianglesPerNode || node.ElementCount() == 0 {
+	bvh.splitNode(node)
+	bvh.invalidateFlatTree()
+}
+
+// splitNode is SplitNode without discarding the flattened tree, for use while building
+func (bvh *BVH) splitNode(node *Node) {
+	if node.ElementCount() == 0 {
+		return
+	}
+	if bvh.options.MaxDepth > 0 && node.Level >= bvh.options.MaxDepth {
 		return
 	}
 
+	var leftElements, rightElements []int
+	var ok bool
+
+	switch bvh.options.SplitStrategy {
+	case SplitSAH:
+		leftElements, rightElements, ok = bvh.partitionSAH(node)
+	case SplitBinnedSAH:
+		leftElements, rightElements, ok = bvh.partitionBinnedSAH(node)
+	default:
+		leftElements, rightElements, ok = bvh.partitionMidpoint(node)
+	}
+
+	if !ok {
+		return
+	}
+
+	bvh.applyPartition(node, leftElements, rightElements)
+}
+
+// partitionMidpoint splits a node at the spatial center of its extents if it contains more elements than maxTrianglesPerNode
+func (bvh *BVH) partitionMidpoint(node *Node) ([]int, []int, bool) {
+	if node.ElementCount() <= bvh.options.MaxTrianglesPerNode {
+		return nil, nil, false
+	}
+
 	startIndex := node.StartIndex
 	endIndex := node.EndIndex
 
@@ -237,7 +380,7 @@ func (bvh *BVH) SplitNode(node *Node) {
 	}
 
 	if splitFailed[0] && splitFailed[1] && splitFailed[2] {
-		return
+		return nil, nil, false
 	}
 
 	splitOrder := []int{0, 1, 2}
@@ -245,16 +388,20 @@ func (bvh *BVH) SplitNode(node *Node) {
 		return extentsLength[splitOrder[j]] > extentsLength[splitOrder[i]]
 	})
 
-	var leftElements, rightElements []int
-
 	for _, candidateIndex := range splitOrder {
 		if !splitFailed[candidateIndex] {
-			leftElements = leftNode[candidateIndex]
-			rightElements = rightNode[candidateIndex]
-			break
+			return leftNode[candidateIndex], rightNode[candidateIndex], true
 		}
 	}
 
+	return nil, nil, false
+}
+
+// applyPartition reorders the node's bounding boxes so that leftElements precede rightElements and creates the two child nodes
+func (bvh *BVH) applyPartition(node *Node, leftElements, rightElements []int) {
+	startIndex := node.StartIndex
+	endIndex := node.EndIndex
+
 	node0Start := startIndex
 	node0End := node0Start + len(leftElements)
 	node1Start := node0End
@@ -271,8 +418,8 @@ func (bvh *BVH) SplitNode(node *Node) {
 	subArr := bvh.bboxHelper[node.StartIndex*7 : node.EndIndex*7]
 	copy(bvh.bboxArray[node.StartIndex*7:], subArr)
 
-	node0Extents := bvh.CalcExtents(node0Start, node0End, EPSILON)
-	node1Extents := bvh.CalcExtents(node1Start, node1End, EPSILON)
+	node0Extents := bvh.CalcExtents(node0Start, node0End, bvh.options.Padding)
+	node1Extents := bvh.CalcExtents(node1Start, node1End, bvh.options.Padding)
 
 	node0 := NewBVHNode(node0Extents[0], node0Extents[1], node0Start, node0End, node.Level+1)
 	node1 := NewBVHNode(node1Extents[0], node1Extents[1], node1Start, node1End, node.Level+1)
@@ -280,8 +427,6 @@ func (bvh *BVH) SplitNode(node *Node) {
 	node.Node0 = node0
 	node.Node1 = node1
 	node.ClearShapes()
-
-	bvh.nodesToSplit = append(bvh.nodesToSplit, node0, node1)
 }
 
 // TValues represents the tmin and tmax values for ray-box intersection
@@ -302,47 +447,73 @@ func CalcTValues(minVal, maxVal, rayOriginCoord, invdir float64) TValues {
 	return res
 }
 
-func isNaN(f float64) bool {
-	return math.IsInf(f, 1) || math.IsInf(f, -1)
-}
-
 // IntersectNodeBox checks if a ray intersects with a node's bounding box
 func IntersectNodeBox(rayOrigin, invRayDirection Point, node *Node) bool {
-	t := CalcTValues(node.ExtentsMin.X, node.ExtentsMax.X, rayOrigin.X, invRayDirection.X)
-	ty := CalcTValues(node.ExtentsMin.Y, node.ExtentsMax.Y, rayOrigin.Y, invRayDirection.Y)
+	return IntersectNodeBoxRange(rayOrigin, invRayDirection, node, 0, math.Inf(1))
+}
 
-	if t.Min > ty.Max || ty.Min > t.Max {
-		return false
-	}
+// IntersectNodeBoxRange checks if a ray intersects with a node's bounding box between the ray parameters tmin and tmax
+func IntersectNodeBoxRange(rayOrigin, invRayDirection Point, node *Node, tmin, tmax float64) bool {
+	_, ok := intersectNodeBoxT(rayOrigin, invRayDirection, node, tmin, tmax)
+	return ok
+}
 
-	if ty.Min > t.Min || isNaN(t.Min) {
-		t.Min = ty.Min
-	}
-	if ty.Max < t.Max || isNaN(t.Max) {
-		t.Max = ty.Max
+// intersectNodeBoxT checks if a ray intersects with a node's bounding box between the ray parameters tmin and tmax
+// and returns the part of [tmin, tmax] during which the ray is inside the box.
+// Points on the faces of the box count as inside.
+func intersectNodeBoxT(rayOrigin, invRayDirection Point, node *Node, tmin, tmax float64) (TValues, bool) {
+	t := TValues{Min: tmin, Max: tmax}
+
+	if !clipSlab(&t, node.ExtentsMin.X, node.ExtentsMax.X, rayOrigin.X, invRayDirection.X) ||
+		!clipSlab(&t, node.ExtentsMin.Y, node.ExtentsMax.Y, rayOrigin.Y, invRayDirection.Y) ||
+		!clipSlab(&t, node.ExtentsMin.Z, node.ExtentsMax.Z, rayOrigin.Z, invRayDirection.Z) {
+		return t, false
 	}
 
-	tz := CalcTValues(node.ExtentsMin.Z, node.ExtentsMax.Z, rayOrigin.Z, invRayDirection.Z)
+	return t, true
+}
 
-	if t.Min > tz.Max || tz.Min > t.Max {
+// clipSlab narrows t to the ray parameters where the ray lies between minVal and maxVal on one axis
+// and reports whether any ray parameters are left
+func clipSlab(t *TValues, minVal, maxVal, rayOriginCoord, invdir float64) bool {
+	if math.IsNaN(invdir) {
 		return false
 	}
-	if tz.Min > t.Min || isNaN(t.Min) {
-		t.Min = tz.Min
-	}
-	if tz.Max < t.Max || isNaN(t.Max) {
-		t.Max = tz.Max
+
+	// A zero direction component makes the ray parallel to the slab, so it is either inside for every t or never.
+	// Handling it separately avoids 0 * Inf = NaN when the origin lies on one of the slab planes.
+	if math.IsInf(invdir, 0) {
+		return rayOriginCoord >= minVal && rayOriginCoord <= maxVal && t.Min <= t.Max
 	}
 
-	if t.Max < 0 {
-		return false
+	slab := CalcTValues(minVal, maxVal, rayOriginCoord, invdir)
+	if slab.Min > t.Min {
+		t.Min = slab.Min
+	}
+	if slab.Max < t.Max {
+		t.Max = slab.Max
 	}
 
-	return true
+	return t.Min <= t.Max
 }
 
 // IntersectRayTriangle determines if a ray intersects with a triangle in 3D space
 func IntersectRayTriangle(a, b, c, rayOrigin, rayDirection Point, backfaceCulling bool) Point {
+	return IntersectRayTriangleRange(a, b, c, rayOrigin, rayDirection, 0, math.Inf(1), backfaceCulling)
+}
+
+// IntersectRayTriangleRange determines if a ray intersects with a triangle between the ray parameters tmin and tmax
+func IntersectRayTriangleRange(a, b, c, rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) Point {
+	hit, ok := intersectRayTriangleHit(a, b, c, rayOrigin, rayDirection, tmin, tmax, backfaceCulling)
+	if !ok {
+		return nil
+	}
+	return pointOnRay(rayOrigin, rayDirection, hit.t)
+}
+
+// intersectRayTriangleHit determines if a ray intersects with a triangle between the ray parameters tmin and tmax
+// and returns the ray parameter and barycentric coordinates of the intersection
+func intersectRayTriangleHit(a, b, c, rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) (triangleHit, bool) {
 	// Compute the offset origin, edges, and normal.
 	var diff = &Vector3{}
 	var edge1 = &Vector3{}
@@ -364,14 +535,14 @@ func IntersectRayTriangle(a, b, c, rayOrigin, rayDirection Point, backfaceCullin
 
 	if DdN > 0 {
 		if backfaceCulling {
-			return nil
+			return triangleHit{}, false
 		}
 		sign = 1
 	} else if DdN < 0 {
 		sign = -1
 		DdN = -DdN
 	} else {
-		return nil
+		return triangleHit{}, false
 	}
 
 	diff.SubVectors(rayOrigin, a)
@@ -379,31 +550,53 @@ func IntersectRayTriangle(a, b, c, rayOrigin, rayDirection Point, backfaceCullin
 
 	// b1 < 0, no intersection
 	if DdQxE2 < 0 {
-		return nil
+		return triangleHit{}, false
 	}
 
 	DdE1xQ := sign * rayDirection.Dot(edge1.Cross(diff))
 
 	// b2 < 0, no intersection
 	if DdE1xQ < 0 {
-		return nil
+		return triangleHit{}, false
 	}
 
 	// b1+b2 > 1, no intersection
 	if DdQxE2+DdE1xQ > DdN {
-		return nil
+		return triangleHit{}, false
 	}
 
 	// Line intersects triangle, check if ray does.
 	QdN := -sign * diff.Dot(normal)
 
-	// t < 0, no intersection
-	if QdN < 0 {
-		return nil
+	// t outside of [tmin, tmax], no intersection
+	t := QdN / DdN
+	if t < tmin || t > tmax {
+		return triangleHit{}, false
 	}
 
 	// Ray intersects triangle.
-	t := QdN / DdN
+	return triangleHit{
+		t:         t,
+		u:         DdQxE2 / DdN,
+		v:         DdE1xQ / DdN,
+		frontFace: sign < 0,
+	}, true
+}
+
+// CalcTriangleNormal calculates the unit normal of the triangle a, b, c following its winding order
+func CalcTriangleNormal(a, b, c Point) Point {
+	return calcTriangleNormalInto(&Vector3{}, a, b, c)
+}
+
+// calcTriangleNormalInto calculates the unit normal of the triangle a, b, c and stores it in normal
+func calcTriangleNormalInto(normal, a, b, c Point) Point {
+	edge2 := Vector3{}
+	edge2.SubVectors(c, a)
+	return normal.SubVectors(b, a).Cross(&edge2).Normalize()
+}
+
+// pointOnRay returns the point at ray parameter t
+func pointOnRay(rayOrigin, rayDirection Point, t float64) Point {
 	result := &Vector3{0, 0, 0}
 	result.X = rayDirection.X*t + rayOrigin.X
 	result.Y = rayDirection.Y*t + rayOrigin.Y
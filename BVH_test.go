@@ -0,0 +1,492 @@
+package bvhtree
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// sphereMesh returns the vertex array of a closed UV sphere around the origin
+func sphereMesh(rings, segments int, radius float64) []float64 {
+	vertex := func(ring, segment int) [3]float64 {
+		// The poles are shared by all segments
+		if ring == 0 || ring == rings {
+			return [3]float64{0, radius * math.Cos(math.Pi*float64(ring)/float64(rings)), 0}
+		}
+		theta := math.Pi * float64(ring) / float64(rings)
+		phi := 2 * math.Pi * float64(segment%segments) / float64(segments)
+		return [3]float64{
+			radius * math.Sin(theta) * math.Cos(phi),
+			radius * math.Cos(theta),
+			radius * math.Sin(theta) * math.Sin(phi),
+		}
+	}
+
+	var vertexArray []float64
+	appendTriangle := func(a, b, c [3]float64) {
+		vertexArray = append(vertexArray, a[0], a[1], a[2], b[0], b[1], b[2], c[0], c[1], c[2])
+	}
+
+	for ring := 0; ring < rings; ring++ {
+		for segment := 0; segment < segments; segment++ {
+			a, b := vertex(ring, segment), vertex(ring, segment+1)
+			c, d := vertex(ring+1, segment), vertex(ring+1, segment+1)
+			if ring > 0 {
+				appendTriangle(a, b, c)
+			}
+			if ring < rings-1 {
+				appendTriangle(b, d, c)
+			}
+		}
+	}
+
+	return vertexArray
+}
+
+// randomTriangles returns the vertex array of count random triangles with edges up to size inside a cube of side 100
+func randomTriangles(count int, size float64, seed int64) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	vertexArray := make([]float64, 0, count*9)
+
+	for i := 0; i < count; i++ {
+		x, y, z := r.Float64()*100-50, r.Float64()*100-50, r.Float64()*100-50
+		for v := 0; v < 3; v++ {
+			vertexArray = append(vertexArray, x+r.Float64()*size, y+r.Float64()*size, z+r.Float64()*size)
+		}
+	}
+
+	return vertexArray
+}
+
+// randomRays returns count rays starting outside a cube of side 100 around the origin and pointing into it
+func randomRays(count int, seed int64) []Ray {
+	r := rand.New(rand.NewSource(seed))
+	rays := make([]Ray, count)
+
+	for i := range rays {
+		origin := NewPoint(r.Float64()*300-150, r.Float64()*300-150, r.Float64()*300-150)
+		target := NewPoint(r.Float64()*100-50, r.Float64()*100-50, r.Float64()*100-50)
+		rays[i] = NewRay(origin, (&Vector3{}).SubVectors(target, origin))
+	}
+
+	return rays
+}
+
+// newSAHBVH builds a BVH with the binned SAH split strategy and otherwise default options
+func newSAHBVH(vertexArray []float64) *BVH {
+	options := DefaultBuildOptions()
+	options.SplitStrategy = SplitBinnedSAH
+	return NewBVHFromVertexArrayWithOptions(vertexArray, options)
+}
+
+// checkQueriesBruteForce fails the test if ray, box or closest point queries disagree with testing every triangle in live
+func checkQueriesBruteForce(t *testing.T, bvh *BVH, live []int, seed int64) {
+	t.Helper()
+
+	triangles := append([]int(nil), live...)
+	sort.Ints(triangles)
+
+	for _, ray := range randomRays(20, seed) {
+		var expected []int
+		closest := math.Inf(1)
+		for _, triIndex := range triangles {
+			tri := bvh.Triangle(triIndex)
+			if hit, ok := intersectRayTriangleHit(tri[0], tri[1], tri[2], ray.Origin, ray.Direction, ray.TMin, ray.TMax, false); ok {
+				expected = append(expected, triIndex)
+				closest = math.Min(closest, hit.t)
+			}
+		}
+
+		var hits []int
+		for _, result := range bvh.IntersectRay(ray.Origin, ray.Direction, false) {
+			hits = append(hits, result.TriangleIndex)
+		}
+		checkSameTriangles(t, "IntersectRay", hits, expected)
+
+		result := bvh.IntersectRayClosest(ray.Origin, ray.Direction, false)
+		if (result == nil) != (len(expected) == 0) || (result != nil && result.Distance != closest) {
+			t.Fatalf("IntersectRayClosest = %+v, expected distance %v", result, closest)
+		}
+
+		// The ray origins double as query points
+		boxMin := NewPoint(ray.Origin.X/3-10, ray.Origin.Y/3-10, ray.Origin.Z/3-10)
+		boxMax := NewPoint(ray.Origin.X/3+10, ray.Origin.Y/3+10, ray.Origin.Z/3+10)
+		expected = expected[:0]
+		for _, triIndex := range triangles {
+			tri := bvh.Triangle(triIndex)
+			if TriangleIntersectsBox(tri[0], tri[1], tri[2], boxMin, boxMax) {
+				expected = append(expected, triIndex)
+			}
+		}
+		checkSameTriangles(t, "QueryAABB", bvh.QueryAABB(boxMin, boxMax, false), expected)
+
+		center := NewPoint(ray.Origin.X/3, ray.Origin.Y/3, ray.Origin.Z/3)
+		expected = expected[:0]
+		for _, triIndex := range triangles {
+			tri := bvh.Triangle(triIndex)
+			point := ClosestPointOnTriangle(center, tri[0], tri[1], tri[2])
+			if dx, dy, dz := point.X-center.X, point.Y-center.Y, point.Z-center.Z; dx*dx+dy*dy+dz*dz <= 100 {
+				expected = append(expected, triIndex)
+			}
+		}
+		var contacts []int
+		for _, contact := range bvh.QuerySphere(center, 10) {
+			contacts = append(contacts, contact.TriangleIndex)
+		}
+		checkSameTriangles(t, "QuerySphere", contacts, expected)
+
+		expectedDistanceSq := bruteForceClosestDistanceSq(bvh.VertexArray(), triangles, ray.Origin)
+		closestPoint := bvh.ClosestPoint(ray.Origin, math.Inf(1))
+		if (closestPoint == nil) != (len(triangles) == 0) ||
+			(closestPoint != nil && math.Abs(closestPoint.DistanceSq-expectedDistanceSq) > 1e-9*expectedDistanceSq) {
+			t.Fatalf("ClosestPoint = %+v, expected squared distance %v", closestPoint, expectedDistanceSq)
+		}
+	}
+
+	var expectedPairs []TrianglePair
+	var a, b [3]Vector3
+	for i, triIndex0 := range triangles {
+		for _, triIndex1 := range triangles[i+1:] {
+			for k := 0; k < 3; k++ {
+				a[k].SetFromArray(bvh.vertexArray, triIndex0*9+k*3)
+				b[k].SetFromArray(bvh.vertexArray, triIndex1*9+k*3)
+			}
+			if neighbourTrianglesIntersect(&a, &b) {
+				expectedPairs = append(expectedPairs, TrianglePair{triIndex0, triIndex1})
+			}
+		}
+	}
+	pairs := bvh.SelfIntersections()
+	sortPairs(pairs)
+	if len(pairs) != len(expectedPairs) || (len(pairs) > 0 && !reflect.DeepEqual(pairs, expectedPairs)) {
+		t.Fatalf("SelfIntersections reported %d pairs, expected %d", len(pairs), len(expectedPairs))
+	}
+}
+
+func TestIntersectNodeBoxBoundaryRays(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	nan := math.NaN()
+	inf := math.Inf(1)
+
+	// The box spans [0, 1] on every axis
+	tests := []struct {
+		name       string
+		origin     [3]float64
+		direction  [3]float64
+		tmin, tmax float64
+		hit        bool
+		enter      float64 // Ray parameter at which the ray enters the box, checked for hits only
+	}{
+		{"through center", [3]float64{-1, 0.5, 0.5}, [3]float64{1, 0, 0}, 0, inf, true, 1},
+		{"through center backwards", [3]float64{2, 0.5, 0.5}, [3]float64{-1, 0, 0}, 0, inf, true, 1},
+		{"pointing away", [3]float64{2, 0.5, 0.5}, [3]float64{1, 0, 0}, 0, inf, false, 0},
+		{"origin inside", [3]float64{0.5, 0.5, 0.5}, [3]float64{1, 2, 3}, 0, inf, true, 0},
+		{"diagonal through corners", [3]float64{-1, -1, -1}, [3]float64{1, 1, 1}, 0, inf, true, 1},
+
+		{"along min face", [3]float64{-1, 0, 0.5}, [3]float64{1, 0, 0}, 0, inf, true, 1},
+		{"along max face", [3]float64{-1, 1, 0.5}, [3]float64{1, 0, 0}, 0, inf, true, 1},
+		{"along max edge with negative zeros", [3]float64{-1, 1, 1}, [3]float64{1, negZero, negZero}, 0, inf, true, 1},
+		{"along min face with negative zero", [3]float64{0.5, 0, -1}, [3]float64{0, negZero, 1}, 0, inf, true, 1},
+		{"parallel just above max face", [3]float64{-1, 1 + 1e-9, 0.5}, [3]float64{1, 0, 0}, 0, inf, false, 0},
+		{"parallel just below min face with negative zero", [3]float64{-1, -1e-9, 0.5}, [3]float64{1, negZero, 0}, 0, inf, false, 0},
+
+		{"origin on min face leaving", [3]float64{0, 0.5, 0.5}, [3]float64{-1, 0, 0}, 0, inf, true, 0},
+		{"origin on max face leaving", [3]float64{1, 0.5, 0.5}, [3]float64{1, 0, 0}, 0, inf, true, 0},
+		{"origin on min corner leaving", [3]float64{0, 0, 0}, [3]float64{-1, -1, -1}, 0, inf, true, 0},
+		{"origin on max corner entering", [3]float64{1, 1, 1}, [3]float64{-1, negZero, -1}, 0, inf, true, 0},
+
+		{"zero direction inside", [3]float64{0.5, 0.5, 0.5}, [3]float64{0, negZero, 0}, 0, inf, true, 0},
+		{"zero direction on face", [3]float64{1, 0.5, 0}, [3]float64{0, 0, 0}, 0, inf, true, 0},
+		{"zero direction outside", [3]float64{1.5, 0.5, 0.5}, [3]float64{0, 0, 0}, 0, inf, false, 0},
+		{"zero direction inside with empty interval", [3]float64{0.5, 0.5, 0.5}, [3]float64{0, 0, 0}, 2, 1, false, 0},
+		{"zero direction on face with empty interval", [3]float64{1, 0.5, 0}, [3]float64{negZero, 0, 0}, 2, 1, false, 0},
+
+		{"NaN direction outside", [3]float64{-1, 0.5, 0.5}, [3]float64{1, nan, 0}, 0, inf, false, 0},
+		{"NaN direction inside", [3]float64{0.5, 0.5, 0.5}, [3]float64{nan, 1, 1}, 0, inf, false, 0},
+		{"NaN direction on face", [3]float64{0, 0.5, 0.5}, [3]float64{1, 0, nan}, 0, inf, false, 0},
+
+		{"tmax before box", [3]float64{-1, 0.5, 0.5}, [3]float64{1, 0, 0}, 0, 0.5, false, 0},
+		{"tmax on entry", [3]float64{-1, 0.5, 0.5}, [3]float64{1, 0, 0}, 0, 1, true, 1},
+		{"tmin on exit", [3]float64{-1, 0.5, 0.5}, [3]float64{1, 0, 0}, 2, inf, true, 2},
+		{"tmin past box", [3]float64{-1, 0.5, 0.5}, [3]float64{1, 0, 0}, 2.5, inf, false, 0},
+		{"interval inside box", [3]float64{-1, 0.5, 0.5}, [3]float64{1, 0, 0}, 1.25, 1.75, true, 1.25},
+		{"negative interval behind origin", [3]float64{2, 0.5, 0.5}, [3]float64{1, 0, 0}, -2, -1, true, -2},
+		{"empty interval", [3]float64{-1, 0.5, 0.5}, [3]float64{1, 0, 0}, 1.5, 1.25, false, 0},
+	}
+
+	node := NewBVHNode(NewPoint(0, 0, 0), NewPoint(1, 1, 1), 0, 0, 0)
+	flat := flatNode{
+		min: [3]float32{roundDown(node.ExtentsMin.X), roundDown(node.ExtentsMin.Y), roundDown(node.ExtentsMin.Z)},
+		max: [3]float32{roundUp(node.ExtentsMax.X), roundUp(node.ExtentsMax.Y), roundUp(node.ExtentsMax.Z)},
+	}
+
+	for _, test := range tests {
+		origin := NewPoint(test.origin[0], test.origin[1], test.origin[2])
+		direction := NewPoint(test.direction[0], test.direction[1], test.direction[2])
+		invDirection := NewPoint(1/direction.X, 1/direction.Y, 1/direction.Z)
+
+		if hit := IntersectNodeBoxRange(origin, invDirection, node, test.tmin, test.tmax); hit != test.hit {
+			t.Errorf("%s: IntersectNodeBoxRange = %v, expected %v", test.name, hit, test.hit)
+		}
+		if tValues, hit := intersectNodeBoxT(origin, invDirection, node, test.tmin, test.tmax); hit && test.hit && tValues.Min != test.enter {
+			t.Errorf("%s: intersectNodeBoxT enters at %v, expected %v", test.name, tValues.Min, test.enter)
+		}
+
+		ray := newFlatRay(origin, direction)
+		enter, hit := intersectFlatNodeT(&ray, &flat, test.tmin, test.tmax)
+		if hit != test.hit {
+			t.Errorf("%s: intersectFlatNodeT = %v, expected %v", test.name, hit, test.hit)
+		} else if hit && enter != test.enter {
+			t.Errorf("%s: intersectFlatNodeT enters at %v, expected %v", test.name, enter, test.enter)
+		}
+	}
+}
+
+// pointerNodeT is a node of the pointer tree together with the ray parameter at which the ray enters its bounding box
+type pointerNodeT struct {
+	node *Node
+	t    float64
+}
+
+// pointerIntersectRay does the same as intersectRay but traverses the Node tree instead of the flattened tree
+func pointerIntersectRay(bvh *BVH, ctx *QueryContext, stack []*Node, ray Ray) ([]IntersectionResult, []*Node) {
+	ctx.vectors.reset()
+	invRayDirection := &Vector3{X: 1 / ray.Direction.X, Y: 1 / ray.Direction.Y, Z: 1 / ray.Direction.Z}
+	nodesToIntersect := append(stack[:0], bvh.rootNode)
+	trianglesInIntersectingNodes := ctx.triangles[:0]
+
+	for len(nodesToIntersect) > 0 {
+		node := nodesToIntersect[len(nodesToIntersect)-1]
+		nodesToIntersect = nodesToIntersect[:len(nodesToIntersect)-1]
+
+		if !IntersectNodeBoxRange(ray.Origin, invRayDirection, node, ray.TMin, ray.TMax) {
+			continue
+		}
+		if node.Node0 != nil {
+			nodesToIntersect = append(nodesToIntersect, node.Node0, node.Node1)
+			continue
+		}
+		for i := node.StartIndex; i < node.EndIndex; i++ {
+			trianglesInIntersectingNodes = append(trianglesInIntersectingNodes, int(bvh.bboxArray[i*7]))
+		}
+	}
+	ctx.triangles = trianglesInIntersectingNodes
+
+	var a, b, c Vector3
+	results := ctx.results[:0]
+	for _, triIndex := range trianglesInIntersectingNodes {
+		a.SetFromArray(bvh.vertexArray, triIndex*9)
+		b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+		c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+		if hit, ok := bvh.intersectTriangle(&a, &b, &c, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false); ok {
+			results = append(results, bvh.newIntersectionResult(&ctx.vectors, triIndex, ray.Origin, ray.Direction, hit))
+		}
+	}
+	ctx.results = results
+
+	return results, nodesToIntersect
+}
+
+// pointerIntersectRayClosest does the same as intersectRayClosest but traverses the Node tree instead of the flattened tree
+func pointerIntersectRayClosest(bvh *BVH, stack []pointerNodeT, ray Ray) (int, triangleHit, []pointerNodeT) {
+	invRayDirection := &Vector3{X: 1 / ray.Direction.X, Y: 1 / ray.Direction.Y, Z: 1 / ray.Direction.Z}
+	closestDistance := ray.TMax
+	closestIndex := -1
+	var closestHit triangleHit
+
+	rootT, ok := intersectNodeBoxT(ray.Origin, invRayDirection, bvh.rootNode, ray.TMin, ray.TMax)
+	if !ok {
+		return closestIndex, closestHit, stack
+	}
+	nodesToIntersect := append(stack[:0], pointerNodeT{bvh.rootNode, rootT.Min})
+
+	var a, b, c Vector3
+	for len(nodesToIntersect) > 0 {
+		entry := nodesToIntersect[len(nodesToIntersect)-1]
+		nodesToIntersect = nodesToIntersect[:len(nodesToIntersect)-1]
+
+		if entry.t > closestDistance {
+			continue
+		}
+
+		node := entry.node
+		if node.Node0 == nil {
+			for i := node.StartIndex; i < node.EndIndex; i++ {
+				triIndex := int(bvh.bboxArray[i*7])
+				a.SetFromArray(bvh.vertexArray, triIndex*9)
+				b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+				c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+				if hit, ok := bvh.intersectTriangle(&a, &b, &c, ray.Origin, ray.Direction, ray.TMin, closestDistance, false); ok && (closestIndex < 0 || hit.t < closestDistance) {
+					closestDistance = hit.t
+					closestIndex = triIndex
+					closestHit = hit
+				}
+			}
+			continue
+		}
+
+		t0, hit0 := intersectNodeBoxT(ray.Origin, invRayDirection, node.Node0, ray.TMin, closestDistance)
+		t1, hit1 := intersectNodeBoxT(ray.Origin, invRayDirection, node.Node1, ray.TMin, closestDistance)
+
+		if hit0 && hit1 {
+			if t0.Min <= t1.Min {
+				nodesToIntersect = append(nodesToIntersect, pointerNodeT{node.Node1, t1.Min}, pointerNodeT{node.Node0, t0.Min})
+			} else {
+				nodesToIntersect = append(nodesToIntersect, pointerNodeT{node.Node0, t0.Min}, pointerNodeT{node.Node1, t1.Min})
+			}
+		} else if hit0 {
+			nodesToIntersect = append(nodesToIntersect, pointerNodeT{node.Node0, t0.Min})
+		} else if hit1 {
+			nodesToIntersect = append(nodesToIntersect, pointerNodeT{node.Node1, t1.Min})
+		}
+	}
+
+	return closestIndex, closestHit, nodesToIntersect
+}
+
+func TestFlatTreeMatchesPointerTree(t *testing.T) {
+	bvh := newSAHBVH(randomTriangles(2000, 10, 1))
+	flatCtx := NewQueryContext()
+	pointerCtx := NewQueryContext()
+	var stack []*Node
+	var closestStack []pointerNodeT
+
+	for _, ray := range randomRays(500, 2) {
+		var expected []IntersectionResult
+		expected, stack = pointerIntersectRay(bvh, pointerCtx, stack, ray)
+		results := bvh.IntersectRayContext(flatCtx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+		if len(results) != len(expected) {
+			t.Fatalf("flat tree found %d intersections, pointer tree %d", len(results), len(expected))
+		}
+		for i := range results {
+			if results[i].TriangleIndex != expected[i].TriangleIndex || results[i].Distance != expected[i].Distance {
+				t.Fatalf("intersection %d is triangle %d at %v on the flat tree, triangle %d at %v on the pointer tree",
+					i, results[i].TriangleIndex, results[i].Distance, expected[i].TriangleIndex, expected[i].Distance)
+			}
+		}
+
+		var expectedIndex int
+		var expectedHit triangleHit
+		expectedIndex, expectedHit, closestStack = pointerIntersectRayClosest(bvh, closestStack, ray)
+		index, hit := bvh.intersectRayClosest(flatCtx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+		if index != expectedIndex || hit != expectedHit {
+			t.Fatalf("closest hit is triangle %d at %v on the flat tree, triangle %d at %v on the pointer tree", index, hit.t, expectedIndex, expectedHit.t)
+		}
+	}
+}
+
+// benchmarkMesh returns a tree and rays shared by the benchmarks comparing the pointer and the flattened tree
+func benchmarkMesh() (*BVH, []Ray) {
+	vertexArray := append(sphereMesh(128, 256, 40), randomTriangles(20000, 5, 1)...)
+	return newSAHBVH(vertexArray), randomRays(1024, 2)
+}
+
+func BenchmarkIntersectRayPointerTree(b *testing.B) {
+	bvh, rays := benchmarkMesh()
+	ctx := NewQueryContext()
+	var stack []*Node
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, stack = pointerIntersectRay(bvh, ctx, stack, rays[i%len(rays)])
+	}
+}
+
+func BenchmarkIntersectRayFlatTree(b *testing.B) {
+	bvh, rays := benchmarkMesh()
+	ctx := NewQueryContext()
+	bvh.flatTree()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ray := rays[i%len(rays)]
+		bvh.IntersectRayContext(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+	}
+}
+
+func BenchmarkIntersectRayClosestPointerTree(b *testing.B) {
+	bvh, rays := benchmarkMesh()
+	var stack []pointerNodeT
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, stack = pointerIntersectRayClosest(bvh, stack, rays[i%len(rays)])
+	}
+}
+
+func BenchmarkIntersectRayClosestFlatTree(b *testing.B) {
+	bvh, rays := benchmarkMesh()
+	ctx := NewQueryContext()
+	bvh.flatTree()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ray := rays[i%len(rays)]
+		bvh.intersectRayClosest(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+	}
+}
+
+func TestIntersectionResultBarycentrics(t *testing.T) {
+	// The normal of the triangle points along +Z and every ray hits it at (1, 2, 0)
+	bvh := NewBVHFromVertexArray([]float64{0, 0, 0, 4, 0, 0, 0, 4, 0}, 1)
+
+	tests := []struct {
+		name      string
+		origin    Point
+		direction Point
+		distance  float64
+		frontFace bool
+	}{
+		{"front", NewPoint(1, 2, 5), NewPoint(0, 0, -1), 5, true},
+		{"back", NewPoint(1, 2, -5), NewPoint(0, 0, 2), 2.5, false},
+		{"oblique front", NewPoint(3, 4, 2), NewPoint(-1, -1, -1), 2, true},
+	}
+
+	for _, mode := range []TriangleIntersection{IntersectionMoller, IntersectionWatertight} {
+		bvh.SetTriangleIntersection(mode)
+
+		for _, test := range tests {
+			results := bvh.IntersectRay(test.origin, test.direction, false)
+			if len(results) != 1 {
+				t.Fatalf("mode %d, %s: %d intersections, expected 1", mode, test.name, len(results))
+			}
+			result := results[0]
+
+			if math.Abs(result.Distance-test.distance) > 1e-12 || result.FrontFace != test.frontFace {
+				t.Errorf("mode %d, %s: distance %v and front face %v, expected %v and %v",
+					mode, test.name, result.Distance, result.FrontFace, test.distance, test.frontFace)
+			}
+			if *result.Normal != (Vector3{0, 0, 1}) {
+				t.Errorf("mode %d, %s: normal %v, expected (0, 0, 1)", mode, test.name, *result.Normal)
+			}
+
+			// Rebuild the intersection point from the barycentric coordinates: a + U*(b-a) + V*(c-a)
+			tri := result.Triangle
+			for axis := 0; axis < 3; axis++ {
+				a, b, c := tri[0].GetComponent(axis), tri[1].GetComponent(axis), tri[2].GetComponent(axis)
+				rebuilt := a + result.U*(b-a) + result.V*(c-a)
+				if math.Abs(rebuilt-result.IntersectionPoint.GetComponent(axis)) > 1e-12 {
+					t.Errorf("mode %d, %s: U %v and V %v give %v on axis %d, expected %v",
+						mode, test.name, result.U, result.V, rebuilt, axis, result.IntersectionPoint.GetComponent(axis))
+				}
+			}
+			if expected := (Vector3{1, 2, 0}); *result.IntersectionPoint != expected {
+				t.Errorf("mode %d, %s: intersection point %v, expected %v", mode, test.name, *result.IntersectionPoint, expected)
+			}
+
+			closest := bvh.IntersectRayClosest(test.origin, test.direction, false)
+			if closest == nil || closest.U != result.U || closest.V != result.V || closest.FrontFace != result.FrontFace {
+				t.Errorf("mode %d, %s: IntersectRayClosest = %+v, expected %+v", mode, test.name, closest, result)
+			}
+
+			culled := bvh.IntersectRay(test.origin, test.direction, true)
+			if (len(culled) == 1) != test.frontFace {
+				t.Errorf("mode %d, %s: %d intersections with backface culling", mode, test.name, len(culled))
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package bvhtree
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// rayBatchSize is the number of rays a worker takes from the input at once
+const rayBatchSize = 64
+
+// RayQueryOptions controls how IntersectRays casts its rays
+type RayQueryOptions struct {
+	Workers         int  // Number of goroutines casting rays; values below 1 use runtime.GOMAXPROCS
+	BackfaceCulling bool // Ignore triangles facing away from the ray
+	ClosestHit      bool // Only report the intersection closest to each ray's TMin instead of all intersections
+}
+
+// IntersectRays intersects every ray with the BVH using a pool of worker goroutines.
+// The result at index i holds the intersections of rays[i], with at most one entry per ray if ClosestHit is set.
+func (bvh *BVH) IntersectRays(rays []Ray, options RayQueryOptions) [][]IntersectionResult {
+	results := make([][]IntersectionResult, len(rays))
+
+	workers := options.Workers
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if maxWorkers := (len(rays) + rayBatchSize - 1) / rayBatchSize; workers > maxWorkers {
+		workers = maxWorkers
+	}
+
+	var nextRay int64
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := NewQueryContext()
+
+			for {
+				end := int(atomic.AddInt64(&nextRay, rayBatchSize))
+				start := end - rayBatchSize
+				if start >= len(rays) {
+					return
+				}
+				if end > len(rays) {
+					end = len(rays)
+				}
+
+				for i := start; i < end; i++ {
+					results[i] = bvh.castRay(ctx, rays[i], options)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// castRay intersects a single ray of a batch using the worker's query context.
+// The results are allocated on the heap since they outlive the next query.
+func (bvh *BVH) castRay(ctx *QueryContext, ray Ray, options RayQueryOptions) []IntersectionResult {
+	if !options.ClosestHit {
+		return bvh.intersectRay(ctx, nil, ray.Origin, ray.Direction, ray.TMin, ray.TMax, options.BackfaceCulling, nil)
+	}
+
+	closestIndex, closestHit := bvh.intersectRayClosest(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, options.BackfaceCulling)
+	if closestIndex < 0 {
+		return nil
+	}
+	return []IntersectionResult{bvh.newIntersectionResult(nil, closestIndex, ray.Origin, ray.Direction, closestHit)}
+}
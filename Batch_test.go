@@ -0,0 +1,57 @@
+package bvhtree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIntersectRays(t *testing.T) {
+	bvh := newSAHBVH(randomTriangles(2000, 10, 1))
+
+	// Enough rays for several batches per worker, some of them limited to a part of the ray
+	rays := randomRays(20*rayBatchSize+7, 2)
+	for i := range rays {
+		if i%3 == 0 {
+			rays[i].TMin, rays[i].TMax = 0.4, 0.8
+		}
+	}
+
+	for _, options := range []RayQueryOptions{
+		{Workers: 4},
+		{Workers: 4, ClosestHit: true},
+		{Workers: 0, BackfaceCulling: true},
+		{Workers: 3, BackfaceCulling: true, ClosestHit: true},
+	} {
+		results := bvh.IntersectRays(rays, options)
+		if len(results) != len(rays) {
+			t.Fatalf("%+v: %d results for %d rays", options, len(results), len(rays))
+		}
+
+		hits := 0
+		for i, ray := range rays {
+			var expected []IntersectionResult
+			if options.ClosestHit {
+				if result := bvh.IntersectRayClosestRange(ray.Origin, ray.Direction, ray.TMin, ray.TMax, options.BackfaceCulling); result != nil {
+					expected = []IntersectionResult{*result}
+				}
+			} else {
+				expected = bvh.IntersectRayRange(ray.Origin, ray.Direction, ray.TMin, ray.TMax, options.BackfaceCulling)
+			}
+
+			if !reflect.DeepEqual(results[i], expected) {
+				t.Fatalf("%+v: ray %d has %d intersections, expected %d", options, i, len(results[i]), len(expected))
+			}
+			hits += len(expected)
+		}
+		if hits == 0 {
+			t.Fatalf("%+v: no ray hit a triangle", options)
+		}
+	}
+
+	if results := bvh.IntersectRays(nil, RayQueryOptions{}); len(results) != 0 {
+		t.Errorf("IntersectRays(nil) = %v, expected no results", results)
+	}
+	if results := bvh.IntersectRays([]Ray{}, RayQueryOptions{Workers: 4, ClosestHit: true}); len(results) != 0 {
+		t.Errorf("IntersectRays([]Ray{}) = %v, expected no results", results)
+	}
+}
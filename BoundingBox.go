@@ -1,5 +1,7 @@
 package bvhtree
 
+import "math"
+
 // SetBox sets the bounding box information in the bboxArray at the specified position
 func SetBox(bboxArray []float64, pos int, triangleID int, minX, minY, minZ, maxX, maxY, maxZ float64) {
 	bboxArray[pos*7] = float64(triangleID)
@@ -43,3 +45,78 @@ type BoundingBox struct {
 	MaxY       float64 // Maximum Y coordinate
 	MaxZ       float64 // Maximum Z coordinate
 }
+
+// emptyBox returns a bounding box that contains no points, ready to be expanded
+func emptyBox() BoundingBox {
+	return BoundingBox{
+		TriangleID: -1,
+		MinX:       math.MaxFloat64,
+		MinY:       math.MaxFloat64,
+		MinZ:       math.MaxFloat64,
+		MaxX:       -math.MaxFloat64,
+		MaxY:       -math.MaxFloat64,
+		MaxZ:       -math.MaxFloat64,
+	}
+}
+
+// expandByArray grows the bounding box to contain the box stored in bboxArray at the specified position
+func (box *BoundingBox) expandByArray(bboxArray []float64, pos int) {
+	box.MinX = math.Min(box.MinX, bboxArray[pos*7+1])
+	box.MinY = math.Min(box.MinY, bboxArray[pos*7+2])
+	box.MinZ = math.Min(box.MinZ, bboxArray[pos*7+3])
+	box.MaxX = math.Max(box.MaxX, bboxArray[pos*7+4])
+	box.MaxY = math.Max(box.MaxY, bboxArray[pos*7+5])
+	box.MaxZ = math.Max(box.MaxZ, bboxArray[pos*7+6])
+}
+
+// expandByBox grows the bounding box to contain another bounding box
+func (box *BoundingBox) expandByBox(src *BoundingBox) {
+	box.MinX = math.Min(box.MinX, src.MinX)
+	box.MinY = math.Min(box.MinY, src.MinY)
+	box.MinZ = math.Min(box.MinZ, src.MinZ)
+	box.MaxX = math.Max(box.MaxX, src.MaxX)
+	box.MaxY = math.Max(box.MaxY, src.MaxY)
+	box.MaxZ = math.Max(box.MaxZ, src.MaxZ)
+}
+
+// axisMin returns the minimum coordinate of the bounding box along the given axis
+func (box *BoundingBox) axisMin(axis int) float64 {
+	switch axis {
+	case 0:
+		return box.MinX
+	case 1:
+		return box.MinY
+	default:
+		return box.MinZ
+	}
+}
+
+// axisMax returns the maximum coordinate of the bounding box along the given axis
+func (box *BoundingBox) axisMax(axis int) float64 {
+	switch axis {
+	case 0:
+		return box.MaxX
+	case 1:
+		return box.MaxY
+	default:
+		return box.MaxZ
+	}
+}
+
+// centroid returns the center of the bounding box along the given axis
+func (box *BoundingBox) centroid(axis int) float64 {
+	return (box.axisMin(axis) + box.axisMax(axis)) * 0.5
+}
+
+// SurfaceArea returns the surface area of the bounding box, or 0 if the box is empty
+func (box *BoundingBox) SurfaceArea() float64 {
+	return CalcSurfaceArea(box.MaxX-box.MinX, box.MaxY-box.MinY, box.MaxZ-box.MinZ)
+}
+
+// CalcSurfaceArea calculates the surface area of a box with the given side lengths
+func CalcSurfaceArea(dx, dy, dz float64) float64 {
+	if dx < 0 || dy < 0 || dz < 0 {
+		return 0
+	}
+	return 2 * (dx*dy + dy*dz + dz*dx)
+}
@@ -0,0 +1,79 @@
+package bvhtree
+
+import "sync"
+
+// parallelSplitThreshold is the minimum number of triangles a subtree needs to be split on its own goroutine
+const parallelSplitThreshold = 4096
+
+// buildProgress counts the triangles placed in leaf nodes and reports them to the Progress callback of the build options
+type buildProgress struct {
+	mutex             sync.Mutex
+	trianglesInLeaves int
+	triangleCount     int
+	callback          func(trianglesInLeaves, triangleCount int)
+}
+
+func newBuildProgress(triangleCount int, callback func(trianglesInLeaves, triangleCount int)) *buildProgress {
+	return &buildProgress{
+		triangleCount: triangleCount,
+		callback:      callback,
+	}
+}
+
+// leafCreated records that a leaf node with the given number of triangles has been finished
+func (progress *buildProgress) leafCreated(elementCount int) {
+	if progress.callback == nil {
+		return
+	}
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	progress.trianglesInLeaves += elementCount
+	progress.callback(progress.trianglesInLeaves, progress.triangleCount)
+}
+
+// splitNodes recursively splits the node and its children until every leaf satisfies the build options.
+// Since each node only reorders its own range of the bboxArray, disjoint subtrees are split on separate
+// goroutines when Parallelism allows it, producing the same tree as a serial build.
+func (bvh *BVH) splitNodes(root *Node, progress *buildProgress) {
+	var wg sync.WaitGroup
+	var workers chan struct{}
+	if bvh.options.Parallelism > 1 {
+		workers = make(chan struct{}, bvh.options.Parallelism-1)
+	}
+
+	var splitSubtree func(node *Node)
+	splitSubtree = func(node *Node) {
+		nodesToSplit := []*Node{node}
+
+		for len(nodesToSplit) > 0 {
+			node := nodesToSplit[len(nodesToSplit)-1]
+			nodesToSplit = nodesToSplit[:len(nodesToSplit)-1]
+			bvh.splitNode(node)
+
+			if node.Node0 == nil {
+				progress.leafCreated(node.ElementCount())
+				continue
+			}
+
+			for _, child := range [2]*Node{node.Node1, node.Node0} {
+				if workers != nil && child.ElementCount() >= parallelSplitThreshold {
+					select {
+					case workers <- struct{}{}:
+						wg.Add(1)
+						go func(child *Node) {
+							defer wg.Done()
+							splitSubtree(child)
+							<-workers
+						}(child)
+						continue
+					default:
+					}
+				}
+				nodesToSplit = append(nodesToSplit, child)
+			}
+		}
+	}
+
+	splitSubtree(root)
+	wg.Wait()
+}
@@ -0,0 +1,50 @@
+package bvhtree
+
+// BuildOptions controls how a BVH is constructed
+type BuildOptions struct {
+	SplitStrategy       SplitStrategy // Strategy used to partition the triangles of a node
+	SAHCost             SAHCost       // Cost constants used by the SAH based split strategies
+	MaxTrianglesPerNode int           // Nodes with more triangles than this are split further
+	MaxDepth            int           // Nodes at this level are never split; 0 means no limit
+	Padding             float64       // Safety margin each node's extents are expanded by
+	Parallelism         int           // Number of goroutines used during construction; values below 2 build serially
+	SpatialSplitOverlap float64       // SplitSpatialSAH only tries spatial splits if the children of the best object split overlap by more than this fraction of the root's surface area
+
+	// Progress, if set, is called whenever triangles end up in a leaf node
+	// with the number of triangles placed in leaves so far and the total triangle count.
+	// Calls are serialized, but may come from different goroutines when Parallelism is above 1.
+	Progress func(trianglesInLeaves, triangleCount int)
+}
+
+// DefaultBuildOptions returns the options used by NewBVH and NewBVHFromVertexArray
+func DefaultBuildOptions() BuildOptions {
+	return BuildOptions{
+		SplitStrategy:       SplitMidpoint,
+		SAHCost:             DefaultSAHCost(),
+		MaxTrianglesPerNode: 8,
+		MaxDepth:            0,
+		Padding:             EPSILON,
+		Parallelism:         1,
+		SpatialSplitOverlap: 1e-5,
+	}
+}
+
+// normalized returns a copy of the options with out of range values clamped to usable ones
+func (options BuildOptions) normalized() BuildOptions {
+	if options.MaxTrianglesPerNode < 1 {
+		options.MaxTrianglesPerNode = 1
+	}
+	if options.MaxDepth < 0 {
+		options.MaxDepth = 0
+	}
+	if options.Padding < 0 {
+		options.Padding = 0
+	}
+	if options.SpatialSplitOverlap < 0 {
+		options.SpatialSplitOverlap = 0
+	}
+	if options.Parallelism < 1 {
+		options.Parallelism = 1
+	}
+	return options
+}
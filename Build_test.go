@@ -0,0 +1,63 @@
+package bvhtree
+
+import (
+	"reflect"
+	"testing"
+)
+
+// checkSameTree fails the test unless both trees have the same nodes with the same extents, ranges and levels
+func checkSameTree(t *testing.T, got, expected *BVH) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got.bboxArray, expected.bboxArray) {
+		t.Fatal("bboxArray differs")
+	}
+
+	nodesToVisit := [][2]*Node{{got.rootNode, expected.rootNode}}
+	for len(nodesToVisit) > 0 {
+		pair := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+		node, expectedNode := pair[0], pair[1]
+
+		if node.StartIndex != expectedNode.StartIndex || node.EndIndex != expectedNode.EndIndex || node.Level != expectedNode.Level ||
+			*node.ExtentsMin != *expectedNode.ExtentsMin || *node.ExtentsMax != *expectedNode.ExtentsMax {
+			t.Fatalf("node [%d, %d) at level %d, expected [%d, %d) at level %d",
+				node.StartIndex, node.EndIndex, node.Level, expectedNode.StartIndex, expectedNode.EndIndex, expectedNode.Level)
+		}
+		if (node.Node0 == nil) != (expectedNode.Node0 == nil) {
+			t.Fatalf("node [%d, %d) is a leaf in only one of the trees", node.StartIndex, node.EndIndex)
+		}
+		if node.Node0 != nil {
+			nodesToVisit = append(nodesToVisit, [2]*Node{node.Node0, expectedNode.Node0}, [2]*Node{node.Node1, expectedNode.Node1})
+		}
+	}
+}
+
+func TestParallelBuildMatchesSerial(t *testing.T) {
+	// Large enough for subtrees of more than parallelSplitThreshold triangles below the root
+	vertexArray := randomTriangles(6*parallelSplitThreshold, 5, 1)
+	triangleCount := len(vertexArray) / 9
+
+	for _, strategy := range []SplitStrategy{SplitMidpoint, SplitSAH, SplitBinnedSAH} {
+		options := DefaultBuildOptions()
+		options.SplitStrategy = strategy
+		serial := NewBVHFromVertexArrayWithOptions(vertexArray, options)
+
+		calls, lastTrianglesInLeaves, lastTriangleCount := 0, 0, 0
+		options.Parallelism = 8
+		options.Progress = func(trianglesInLeaves, triangleCount int) {
+			if trianglesInLeaves <= lastTrianglesInLeaves {
+				t.Errorf("strategy %d: progress went from %d to %d triangles", strategy, lastTrianglesInLeaves, trianglesInLeaves)
+			}
+			calls++
+			lastTrianglesInLeaves, lastTriangleCount = trianglesInLeaves, triangleCount
+		}
+		parallel := NewBVHFromVertexArrayWithOptions(vertexArray, options)
+
+		if calls == 0 || lastTrianglesInLeaves != triangleCount || lastTriangleCount != triangleCount {
+			t.Errorf("strategy %d: last of %d progress calls reported %d of %d triangles, expected %d of %d",
+				strategy, calls, lastTrianglesInLeaves, lastTriangleCount, triangleCount, triangleCount)
+		}
+		checkSameTree(t, parallel, serial)
+	}
+}
@@ -0,0 +1,183 @@
+package bvhtree
+
+// ClosestPointResult represents the point of the mesh closest to a query point
+type ClosestPointResult struct {
+	TriangleIndex int
+	Point         Point   // Closest point on the triangle
+	DistanceSq    float64 // Squared distance between the query point and Point
+	U, V          float64 // Barycentric coordinates of Point, weights of the triangle's second and third vertex
+}
+
+// ClosestPoint returns the point on the mesh closest to p, or nil if no triangle is within maxDistance of p.
+// Nodes are visited nearest first and skipped once they are farther away than the closest point found so far.
+// A negative maxDistance matches no triangle.
+func (bvh *BVH) ClosestPoint(p Point, maxDistance float64) *ClosestPointResult {
+	if maxDistance < 0 {
+		return nil
+	}
+
+	closestDistanceSq := maxDistance * maxDistance
+	closestIndex := -1
+	var closestU, closestV float64
+
+	nodes := bvh.flatTree()
+	rootDistanceSq := nodes[0].distanceSq(p)
+	if rootDistanceSq > closestDistanceSq {
+		return nil
+	}
+
+	nodesToVisit := []flatNodeDistance{{0, rootDistanceSq}}
+
+	var a, b, c, point, closestPoint Vector3
+
+	for len(nodesToVisit) > 0 {
+		entry := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if entry.distance > closestDistanceSq {
+			continue
+		}
+
+		node := &nodes[entry.index]
+		if node.isLeaf() {
+			for i := node.offset; i < node.offset+node.count; i++ {
+				triIndex := int(bvh.bboxArray[i*7])
+				a.SetFromArray(bvh.vertexArray, triIndex*9)
+				b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+				c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+				u, v := closestPointOnTriangle(p, &a, &b, &c, &point)
+				dx, dy, dz := point.X-p.X, point.Y-p.Y, point.Z-p.Z
+				if distanceSq := dx*dx + dy*dy + dz*dz; distanceSq <= closestDistanceSq && (closestIndex < 0 || distanceSq < closestDistanceSq) {
+					closestDistanceSq = distanceSq
+					closestIndex = triIndex
+					closestU, closestV = u, v
+					closestPoint.Copy(&point)
+				}
+			}
+			continue
+		}
+
+		index0, index1 := node.children()
+		d0 := nodes[index0].distanceSq(p)
+		d1 := nodes[index1].distanceSq(p)
+
+		// Push the farther child first so the nearer one is visited next
+		if d0 <= d1 {
+			nodesToVisit = appendIfWithin(nodesToVisit, flatNodeDistance{index1, d1}, closestDistanceSq)
+			nodesToVisit = appendIfWithin(nodesToVisit, flatNodeDistance{index0, d0}, closestDistanceSq)
+		} else {
+			nodesToVisit = appendIfWithin(nodesToVisit, flatNodeDistance{index0, d0}, closestDistanceSq)
+			nodesToVisit = appendIfWithin(nodesToVisit, flatNodeDistance{index1, d1}, closestDistanceSq)
+		}
+	}
+
+	if closestIndex < 0 {
+		return nil
+	}
+
+	return &ClosestPointResult{
+		TriangleIndex: closestIndex,
+		Point:         closestPoint.Clone(),
+		DistanceSq:    closestDistanceSq,
+		U:             closestU,
+		V:             closestV,
+	}
+}
+
+// appendIfWithin appends entry to nodes unless its distance exceeds maxDistance
+func appendIfWithin(nodes []flatNodeDistance, entry flatNodeDistance, maxDistance float64) []flatNodeDistance {
+	if entry.distance > maxDistance {
+		return nodes
+	}
+	return append(nodes, entry)
+}
+
+// axisDistanceSq returns the squared distance between a coordinate and the interval [minVal, maxVal]
+func axisDistanceSq(coord, minVal, maxVal float64) float64 {
+	if coord < minVal {
+		return (minVal - coord) * (minVal - coord)
+	}
+	if coord > maxVal {
+		return (coord - maxVal) * (coord - maxVal)
+	}
+	return 0
+}
+
+// ClosestPointOnTriangle returns the point of the triangle a, b, c closest to p
+func ClosestPointOnTriangle(p, a, b, c Point) Point {
+	result := &Vector3{}
+	closestPointOnTriangle(p, a, b, c, result)
+	return result
+}
+
+// closestPointOnTriangle stores the point of the triangle a, b, c closest to p in result and returns its
+// barycentric coordinates (u, v), the weights of b and c. It follows the Voronoi region tests described in
+// Ericson's "Real-Time Collision Detection".
+func closestPointOnTriangle(p, a, b, c, result Point) (float64, float64) {
+	var ab, ac, ap Vector3
+	ab.SubVectors(b, a)
+	ac.SubVectors(c, a)
+	ap.SubVectors(p, a)
+
+	// Vertex region of a
+	d1 := ab.Dot(&ap)
+	d2 := ac.Dot(&ap)
+	if d1 <= 0 && d2 <= 0 {
+		result.Copy(a)
+		return 0, 0
+	}
+
+	// Vertex region of b
+	var bp Vector3
+	bp.SubVectors(p, b)
+	d3 := ab.Dot(&bp)
+	d4 := ac.Dot(&bp)
+	if d3 >= 0 && d4 <= d3 {
+		result.Copy(b)
+		return 1, 0
+	}
+
+	// Edge region of ab
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		v := d1 / (d1 - d3)
+		result.Copy(&ab).MultiplyScalar(v).Add(a)
+		return v, 0
+	}
+
+	// Vertex region of c
+	var cp Vector3
+	cp.SubVectors(p, c)
+	d5 := ab.Dot(&cp)
+	d6 := ac.Dot(&cp)
+	if d6 >= 0 && d5 <= d6 {
+		result.Copy(c)
+		return 0, 1
+	}
+
+	// Edge region of ac
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		w := d2 / (d2 - d6)
+		result.Copy(&ac).MultiplyScalar(w).Add(a)
+		return 0, w
+	}
+
+	// Edge region of bc
+	va := d3*d6 - d5*d4
+	if va <= 0 && (d4-d3) >= 0 && (d5-d6) >= 0 {
+		w := (d4 - d3) / ((d4 - d3) + (d5 - d6))
+		result.SubVectors(c, b).MultiplyScalar(w).Add(b)
+		return 1 - w, w
+	}
+
+	// Inside the face
+	denom := 1 / (va + vb + vc)
+	v := vb * denom
+	w := vc * denom
+	ab.MultiplyScalar(v)
+	ac.MultiplyScalar(w)
+	result.Copy(a).Add(&ab).Add(&ac)
+	return v, w
+}
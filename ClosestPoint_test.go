@@ -0,0 +1,53 @@
+package bvhtree
+
+import (
+	"math"
+	"testing"
+)
+
+// bruteForceClosestDistanceSq returns the squared distance between p and the nearest of the given triangles
+// of vertexArray, +Inf if there are none
+func bruteForceClosestDistanceSq(vertexArray []float64, triangles []int, p Point) float64 {
+	closest := math.Inf(1)
+	var a, b, c, point Vector3
+
+	for _, triIndex := range triangles {
+		a.SetFromArray(vertexArray, triIndex*9)
+		b.SetFromArray(vertexArray, triIndex*9+3)
+		c.SetFromArray(vertexArray, triIndex*9+6)
+
+		closestPointOnTriangle(p, &a, &b, &c, &point)
+		dx, dy, dz := point.X-p.X, point.Y-p.Y, point.Z-p.Z
+		closest = math.Min(closest, dx*dx+dy*dy+dz*dz)
+	}
+
+	return closest
+}
+
+func TestClosestPoint(t *testing.T) {
+	vertexArray := randomTriangles(500, 5, 1)
+	bvh := newSAHBVH(vertexArray)
+
+	triangles := make([]int, len(vertexArray)/9)
+	for i := range triangles {
+		triangles[i] = i
+	}
+
+	for _, ray := range randomRays(200, 2) {
+		p := ray.Origin
+		expected := bruteForceClosestDistanceSq(vertexArray, triangles, p)
+
+		result := bvh.ClosestPoint(p, math.Inf(1))
+		if result == nil || math.Abs(result.DistanceSq-expected) > 1e-9*expected {
+			t.Fatalf("ClosestPoint(%v) = %+v, expected squared distance %v", *p, result, expected)
+		}
+
+		distance := math.Sqrt(expected)
+		if result := bvh.ClosestPoint(p, distance*0.99); result != nil {
+			t.Fatalf("ClosestPoint(%v, %v) = %+v, expected nil", *p, distance*0.99, result)
+		}
+		if result := bvh.ClosestPoint(p, -distance*1.01); result != nil {
+			t.Fatalf("ClosestPoint(%v, %v) = %+v, expected nil", *p, -distance*1.01, result)
+		}
+	}
+}
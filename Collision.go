@@ -0,0 +1,247 @@
+package bvhtree
+
+import "math"
+
+// TrianglePair is a pair of intersecting triangles given by their indices
+type TrianglePair struct {
+	Triangle0, Triangle1 int
+}
+
+// flatNodePair is a pair of flat nodes, given by their indices, whose subtrees are tested against each other
+type flatNodePair struct {
+	index0, index1 int32
+}
+
+// IntersectBVH returns all pairs of intersecting triangles between this BVH and other.
+// Triangle0 of each pair belongs to this BVH, Triangle1 to other. The transform maps other into the space of this BVH,
+// nil means both meshes share the same space.
+func (bvh *BVH) IntersectBVH(other *BVH, transform *RigidTransform) []TrianglePair {
+	var pairs []TrianglePair
+	bvh.IntersectBVHFunc(other, transform, func(pair TrianglePair) bool {
+		pairs = append(pairs, pair)
+		return true
+	})
+	return pairs
+}
+
+// IntersectBVHFunc calls callback for every pair of intersecting triangles between this BVH and other, see IntersectBVH.
+// Both trees are traversed simultaneously, always descending into the node with the larger surface area.
+// The query stops early when callback returns false.
+func (bvh *BVH) IntersectBVHFunc(other *BVH, transform *RigidTransform, callback func(pair TrianglePair) bool) {
+	if transform == nil {
+		identity := IdentityTransform()
+		transform = &identity
+	}
+
+	if bvh.duplicateReferences || other.duplicateReferences {
+		callback = uniquePairs(callback)
+	}
+
+	nodes0 := bvh.flatTree()
+	nodes1 := other.flatTree()
+	nodesToVisit := []flatNodePair{{0, 0}}
+
+	var otherMin, otherMax Vector3
+
+	for len(nodesToVisit) > 0 {
+		pair := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		node0, node1 := &nodes0[pair.index0], &nodes1[pair.index1]
+		node1.bounds(&otherMin, &otherMax)
+		transform.ApplyToBox(&otherMin, &otherMax, &otherMin, &otherMax)
+		if !node0.overlapsBox(&otherMin, &otherMax) {
+			continue
+		}
+
+		isLeaf0 := node0.isLeaf()
+		isLeaf1 := node1.isLeaf()
+
+		if isLeaf0 && isLeaf1 {
+			if !bvh.intersectLeaves(other, transform, node0, node1, callback) {
+				return
+			}
+			continue
+		}
+
+		if isLeaf1 || (!isLeaf0 && node0.surfaceArea() >= node1.surfaceArea()) {
+			index0, index1 := node0.children()
+			nodesToVisit = append(nodesToVisit, flatNodePair{index1, pair.index1}, flatNodePair{index0, pair.index1})
+		} else {
+			index0, index1 := node1.children()
+			nodesToVisit = append(nodesToVisit, flatNodePair{pair.index0, index1}, flatNodePair{pair.index0, index0})
+		}
+	}
+}
+
+// uniquePairs wraps callback so that it is called once per pair of triangles referenced from several leaves
+func uniquePairs(callback func(pair TrianglePair) bool) func(pair TrianglePair) bool {
+	reported := map[TrianglePair]struct{}{}
+	return func(pair TrianglePair) bool {
+		if _, ok := reported[pair]; ok {
+			return true
+		}
+		reported[pair] = struct{}{}
+		return callback(pair)
+	}
+}
+
+// intersectLeaves tests the triangles of two leaf nodes against each other and reports whether the query should continue
+func (bvh *BVH) intersectLeaves(other *BVH, transform *RigidTransform, leaf0, leaf1 *flatNode, callback func(pair TrianglePair) bool) bool {
+	var a0, a1, a2, b0, b1, b2, otherMin, otherMax Vector3
+
+	for j := int(leaf1.offset); j < int(leaf1.offset+leaf1.count); j++ {
+		otherIndex := int(other.bboxArray[j*7])
+		b0.SetFromArray(other.vertexArray, otherIndex*9)
+		b1.SetFromArray(other.vertexArray, otherIndex*9+3)
+		b2.SetFromArray(other.vertexArray, otherIndex*9+6)
+		transform.Apply(&b0, &b0)
+		transform.Apply(&b1, &b1)
+		transform.Apply(&b2, &b2)
+
+		otherMin.Set(math.Min(math.Min(b0.X, b1.X), b2.X), math.Min(math.Min(b0.Y, b1.Y), b2.Y), math.Min(math.Min(b0.Z, b1.Z), b2.Z))
+		otherMax.Set(math.Max(math.Max(b0.X, b1.X), b2.X), math.Max(math.Max(b0.Y, b1.Y), b2.Y), math.Max(math.Max(b0.Z, b1.Z), b2.Z))
+
+		for i := int(leaf0.offset); i < int(leaf0.offset+leaf0.count); i++ {
+			if !boxOverlapsArray(bvh.bboxArray, i, &otherMin, &otherMax) {
+				continue
+			}
+
+			triIndex := int(bvh.bboxArray[i*7])
+			a0.SetFromArray(bvh.vertexArray, triIndex*9)
+			a1.SetFromArray(bvh.vertexArray, triIndex*9+3)
+			a2.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+			if TrianglesIntersect(&a0, &a1, &a2, &b0, &b1, &b2) && !callback(TrianglePair{triIndex, otherIndex}) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// TrianglesIntersect checks if the triangles a0, a1, a2 and b0, b1, b2 intersect.
+// Triangles in different planes intersect exactly if an edge of one of them passes through the other.
+// Coplanar triangles are tested in 2D after dropping the dominant axis of their normal.
+func TrianglesIntersect(a0, a1, a2, b0, b1, b2 Point) bool {
+	var edge1, edge2, normalA, normalB, diff Vector3
+
+	normalA.CrossVectors(edge1.SubVectors(a1, a0), edge2.SubVectors(a2, a0))
+	normalB.CrossVectors(edge1.SubVectors(b1, b0), edge2.SubVectors(b2, b0))
+
+	// Triangle A entirely on one side of the plane of B, or the other way around
+	da0 := normalB.Dot(diff.SubVectors(a0, b0))
+	da1 := normalB.Dot(diff.SubVectors(a1, b0))
+	da2 := normalB.Dot(diff.SubVectors(a2, b0))
+	if sameSide(da0, da1, da2) {
+		return false
+	}
+
+	db0 := normalA.Dot(diff.SubVectors(b0, a0))
+	db1 := normalA.Dot(diff.SubVectors(b1, a0))
+	db2 := normalA.Dot(diff.SubVectors(b2, a0))
+	if sameSide(db0, db1, db2) {
+		return false
+	}
+
+	if (da0 == 0 && da1 == 0 && da2 == 0) || (db0 == 0 && db1 == 0 && db2 == 0) {
+		// Project along the normal of the larger triangle, a degenerate one has none
+		if normalB.LengthSq() > normalA.LengthSq() {
+			return coplanarTrianglesIntersect(&normalB, a0, a1, a2, b0, b1, b2)
+		}
+		return coplanarTrianglesIntersect(&normalA, a0, a1, a2, b0, b1, b2)
+	}
+
+	return edgesIntersectTriangle(a0, a1, a2, b0, b1, b2) || edgesIntersectTriangle(b0, b1, b2, a0, a1, a2)
+}
+
+// sameSide checks if three signed plane distances are all strictly positive or all strictly negative
+func sameSide(d0, d1, d2 float64) bool {
+	return (d0 > 0 && d1 > 0 && d2 > 0) || (d0 < 0 && d1 < 0 && d2 < 0)
+}
+
+// edgesIntersectTriangle checks if one of the edges of the triangle a0, a1, a2 intersects the triangle b0, b1, b2
+func edgesIntersectTriangle(a0, a1, a2, b0, b1, b2 Point) bool {
+	return segmentIntersectsTriangle(a0, a1, b0, b1, b2) ||
+		segmentIntersectsTriangle(a1, a2, b0, b1, b2) ||
+		segmentIntersectsTriangle(a2, a0, b0, b1, b2)
+}
+
+// segmentIntersectsTriangle checks if the segment from p to q intersects the triangle a, b, c
+func segmentIntersectsTriangle(p, q, a, b, c Point) bool {
+	var direction Vector3
+	direction.SubVectors(q, p)
+	_, ok := intersectRayTriangleHit(a, b, c, p, &direction, 0, 1, false)
+	return ok
+}
+
+// coplanarTrianglesIntersect checks if two triangles lying in the same plane with the given normal intersect
+func coplanarTrianglesIntersect(normal, a0, a1, a2, b0, b1, b2 Point) bool {
+	project := coplanarProjection(normal)
+	triA := [3][2]float64{project(a0), project(a1), project(a2)}
+	triB := [3][2]float64{project(b0), project(b1), project(b2)}
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if segments2DIntersect(triA[i], triA[(i+1)%3], triB[j], triB[(j+1)%3]) {
+				return true
+			}
+		}
+	}
+
+	return pointInTriangle2D(triA[0], triB) || pointInTriangle2D(triB[0], triA)
+}
+
+// coplanarProjection returns the projection onto the coordinate plane where triangles with the given normal have the largest area
+func coplanarProjection(normal Point) func(p Point) [2]float64 {
+	axisX, axisY := 1, 2
+	nx, ny, nz := math.Abs(normal.X), math.Abs(normal.Y), math.Abs(normal.Z)
+	if ny > nx && ny >= nz {
+		axisX, axisY = 0, 2
+	} else if nz > nx && nz > ny {
+		axisX, axisY = 0, 1
+	}
+
+	return func(p Point) [2]float64 {
+		return [2]float64{p.GetComponent(axisX), p.GetComponent(axisY)}
+	}
+}
+
+// orient2D returns twice the signed area of the triangle a, b, c
+func orient2D(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// segments2DIntersect checks if the segments p0-p1 and q0-q1 intersect, touching counts as intersecting
+func segments2DIntersect(p0, p1, q0, q1 [2]float64) bool {
+	d0 := orient2D(q0, q1, p0)
+	d1 := orient2D(q0, q1, p1)
+	d2 := orient2D(p0, p1, q0)
+	d3 := orient2D(p0, p1, q1)
+
+	if ((d0 > 0 && d1 < 0) || (d0 < 0 && d1 > 0)) && ((d2 > 0 && d3 < 0) || (d2 < 0 && d3 > 0)) {
+		return true
+	}
+
+	return (d0 == 0 && onSegment2D(q0, q1, p0)) ||
+		(d1 == 0 && onSegment2D(q0, q1, p1)) ||
+		(d2 == 0 && onSegment2D(p0, p1, q0)) ||
+		(d3 == 0 && onSegment2D(p0, p1, q1))
+}
+
+// onSegment2D checks if p, known to be collinear with a and b, lies between them
+func onSegment2D(a, b, p [2]float64) bool {
+	return p[0] >= math.Min(a[0], b[0]) && p[0] <= math.Max(a[0], b[0]) &&
+		p[1] >= math.Min(a[1], b[1]) && p[1] <= math.Max(a[1], b[1])
+}
+
+// pointInTriangle2D checks if p lies inside the triangle or on its boundary
+func pointInTriangle2D(p [2]float64, tri [3][2]float64) bool {
+	d0 := orient2D(tri[0], tri[1], p)
+	d1 := orient2D(tri[1], tri[2], p)
+	d2 := orient2D(tri[2], tri[0], p)
+	hasNegative := d0 < 0 || d1 < 0 || d2 < 0
+	hasPositive := d0 > 0 || d1 > 0 || d2 > 0
+	return !(hasNegative && hasPositive)
+}
@@ -0,0 +1,97 @@
+package bvhtree
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestRigidTransform(t *testing.T) {
+	axis := NewPoint(1, 2, 3).Normalize()
+	transform := NewRigidTransformFromAxisAngle(axis, 0.7, NewPoint(5, -3, 2))
+
+	// Points on the axis only move by the translation
+	if p := transform.Apply(NewPoint(2, 4, 6).Normalize(), &Vector3{}); math.Abs(p.X-5-axis.X) > 1e-12 ||
+		math.Abs(p.Y+3-axis.Y) > 1e-12 || math.Abs(p.Z-2-axis.Z) > 1e-12 {
+		t.Errorf("point on the rotation axis moved to %v", *p)
+	}
+
+	// A quarter turn around Z maps X to Y
+	quarterTurn := NewRigidTransformFromAxisAngle(NewPoint(0, 0, 1), math.Pi/2, NewPoint(0, 0, 0))
+	if p := quarterTurn.Apply(NewPoint(1, 0, 0), &Vector3{}); math.Abs(p.X) > 1e-12 || math.Abs(p.Y-1) > 1e-12 || p.Z != 0 {
+		t.Errorf("quarter turn around Z maps (1, 0, 0) to %v", *p)
+	}
+
+	// The transformed box contains every transformed corner and touches the extreme ones
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		extentsMin := NewPoint(r.Float64()*10-5, r.Float64()*10-5, r.Float64()*10-5)
+		extentsMax := NewPoint(extentsMin.X+r.Float64()*5, extentsMin.Y+r.Float64()*5, extentsMin.Z+r.Float64()*5)
+		var resultMin, resultMax Vector3
+		transform.ApplyToBox(extentsMin, extentsMax, &resultMin, &resultMax)
+
+		cornersMin := Vector3{math.Inf(1), math.Inf(1), math.Inf(1)}
+		cornersMax := Vector3{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+		for corner := 0; corner < 8; corner++ {
+			p := NewPoint(extentsMin.X, extentsMin.Y, extentsMin.Z)
+			if corner&1 != 0 {
+				p.X = extentsMax.X
+			}
+			if corner&2 != 0 {
+				p.Y = extentsMax.Y
+			}
+			if corner&4 != 0 {
+				p.Z = extentsMax.Z
+			}
+			transform.Apply(p, p)
+			cornersMin.Set(math.Min(cornersMin.X, p.X), math.Min(cornersMin.Y, p.Y), math.Min(cornersMin.Z, p.Z))
+			cornersMax.Set(math.Max(cornersMax.X, p.X), math.Max(cornersMax.Y, p.Y), math.Max(cornersMax.Z, p.Z))
+		}
+
+		for axis := 0; axis < 3; axis++ {
+			if math.Abs(resultMin.GetComponent(axis)-cornersMin.GetComponent(axis)) > 1e-9 ||
+				math.Abs(resultMax.GetComponent(axis)-cornersMax.GetComponent(axis)) > 1e-9 {
+				t.Fatalf("ApplyToBox gives %v to %v, the transformed corners span %v to %v", resultMin, resultMax, cornersMin, cornersMax)
+			}
+		}
+	}
+}
+
+func TestIntersectBVHTransformed(t *testing.T) {
+	vertexArray := randomTriangles(600, 10, 1)
+	otherVertexArray := randomTriangles(600, 10, 2)
+	transform := NewRigidTransformFromAxisAngle(NewPoint(1, 2, 3).Normalize(), 0.7, NewPoint(5, -3, 2))
+
+	for _, strategy := range []SplitStrategy{SplitMidpoint, SplitBinnedSAH} {
+		options := DefaultBuildOptions()
+		options.SplitStrategy = strategy
+		bvh := NewBVHFromVertexArrayWithOptions(vertexArray, options)
+		other := NewBVHFromVertexArrayWithOptions(otherVertexArray, options)
+
+		var expected []TrianglePair
+		for i := 0; i < len(vertexArray)/9; i++ {
+			a := bvh.Triangle(i)
+			for j := 0; j < len(otherVertexArray)/9; j++ {
+				b := other.Triangle(j)
+				for _, vertex := range b {
+					transform.Apply(vertex, vertex)
+				}
+				if TrianglesIntersect(a[0], a[1], a[2], b[0], b[1], b[2]) {
+					expected = append(expected, TrianglePair{i, j})
+				}
+			}
+		}
+
+		pairs := bvh.IntersectBVH(other, &transform)
+		sortPairs(pairs)
+		if len(expected) == 0 || !reflect.DeepEqual(pairs, expected) {
+			t.Errorf("strategy %d: IntersectBVH() found %d pairs, expected %d", strategy, len(pairs), len(expected))
+		}
+
+		// Without the transform the meshes overlap differently
+		if untransformed := bvh.IntersectBVH(other, nil); reflect.DeepEqual(untransformed, pairs) {
+			t.Errorf("strategy %d: IntersectBVH() found the same pairs with and without the transform", strategy)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package bvhtree
+
+import "math"
+
+// containsRayDirections are the directions of the rays cast by Contains. They point away from the coordinate axes
+// and from each other, so a ray grazing an edge or vertex of the mesh in one direction is unlikely to do so in the others.
+var containsRayDirections = [3]Vector3{
+	{X: 0.6128, Y: 0.5326, Z: 0.5838},
+	{X: -0.4781, Y: 0.7143, Z: -0.5112},
+	{X: 0.3459, Y: -0.6812, Z: -0.6452},
+}
+
+// Contains checks if p lies inside the mesh, which has to be closed.
+// A ray is cast from p in each of three directions, p is inside if an odd number of triangles
+// is crossed by at least two of them. The majority vote hides a ray miscounting a crossing at an edge or vertex.
+func (bvh *BVH) Contains(p Point) bool {
+	root := bvh.rootNode
+	if p.X < root.ExtentsMin.X || p.Y < root.ExtentsMin.Y || p.Z < root.ExtentsMin.Z ||
+		p.X > root.ExtentsMax.X || p.Y > root.ExtentsMax.Y || p.Z > root.ExtentsMax.Z {
+		return false
+	}
+
+	inside := 0
+	for i := range containsRayDirections {
+		if bvh.countRayCrossings(p, &containsRayDirections[i])%2 == 1 {
+			inside++
+		}
+		// The first two rays agree, the third one can't change the outcome
+		if i == 1 && inside != 1 {
+			break
+		}
+	}
+
+	return inside >= 2
+}
+
+// countRayCrossings returns the number of distinct triangles intersected by the ray from rayOrigin along rayDirection.
+// The watertight intersection test is used so the ray can't slip between neighbouring triangles.
+func (bvh *BVH) countRayCrossings(rayOrigin, rayDirection Point) int {
+	ray := newFlatRay(rayOrigin, rayDirection)
+	tmax := math.Inf(1)
+
+	nodes := bvh.flatTree()
+	var stackBuffer [64]int32
+	nodesToIntersect := append(stackBuffer[:0], 0)
+
+	var a, b, c Vector3
+	crossings := 0
+	crossed := bvh.newTriangleSet()
+
+	for len(nodesToIntersect) > 0 {
+		index := nodesToIntersect[len(nodesToIntersect)-1]
+		nodesToIntersect = nodesToIntersect[:len(nodesToIntersect)-1]
+
+		node := &nodes[index]
+		if _, ok := intersectFlatNodeT(&ray, node, 0, tmax); !ok {
+			continue
+		}
+
+		if !node.isLeaf() {
+			index0, index1 := node.children()
+			nodesToIntersect = append(nodesToIntersect, index0, index1)
+			continue
+		}
+
+		for i := node.offset; i < node.offset+node.count; i++ {
+			triIndex := int(bvh.bboxArray[i*7])
+			a.SetFromArray(bvh.vertexArray, triIndex*9)
+			b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+			c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+			if _, ok := intersectRayTriangleWatertightHit(&a, &b, &c, rayOrigin, rayDirection, 0, tmax, false); ok && crossed.add(triIndex) {
+				crossings++
+			}
+		}
+	}
+
+	return crossings
+}
@@ -0,0 +1,51 @@
+package bvhtree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestContainsSphere(t *testing.T) {
+	const radius = 10
+	vertexArray := sphereMesh(16, 32, radius)
+	bvh := newSAHBVH(vertexArray)
+
+	// The faces of the sphere mesh lie between 9.5 and the radius from the center
+	checkContains := func(p Point, description string) {
+		t.Helper()
+		switch distance := p.Length(); {
+		case distance < 9.5:
+			if !bvh.Contains(p) {
+				t.Fatalf("%s %v at distance %v from the center is not inside", description, *p, distance)
+			}
+		case distance > radius:
+			if bvh.Contains(p) {
+				t.Fatalf("%s %v at distance %v from the center is inside", description, *p, distance)
+			}
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		checkContains(NewPoint(r.Float64()*30-15, r.Float64()*30-15, r.Float64()*30-15), "random point")
+	}
+
+	// Points from which one of the rays of Contains passes through a vertex of the mesh, the poles included,
+	// both from inside and from outside the sphere
+	vertices := map[Vector3]bool{}
+	for i := 0; i < len(vertexArray); i += 3 {
+		vertices[Vector3{vertexArray[i], vertexArray[i+1], vertexArray[i+2]}] = true
+	}
+	if !vertices[Vector3{0, radius, 0}] || !vertices[Vector3{0, -radius, 0}] {
+		t.Fatal("sphere mesh has no vertices at the poles")
+	}
+
+	for vertex := range vertices {
+		for i := range containsRayDirections {
+			for _, distance := range []float64{1, 3, 25} {
+				p := (&Vector3{}).Copy(&containsRayDirections[i]).MultiplyScalar(-distance)
+				checkContains(p.Add(&vertex), "point on a ray through a vertex,")
+			}
+		}
+	}
+}
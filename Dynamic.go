@@ -0,0 +1,240 @@
+package bvhtree
+
+// dynamicTree holds the bookkeeping needed to insert and remove triangles.
+// It is set up by the first edit, since a BVH that is never edited doesn't need it.
+type dynamicTree struct {
+	parents         map[*Node]*Node // Parent of every node except the root
+	leaves          map[int][]*Node // Leaves referencing each triangle
+	ownsVertexArray bool            // The vertex array was allocated by the BVH and may be appended to
+	freeIDs         []int           // IDs of removed triangles, reused by Insert before the vertex array grows
+	freeBoxes       []int           // Positions in the bboxArray no leaf refers to anymore
+	flatIndices     map[*Node]int32 // Index of every node in the flattened tree, recorded by flatten
+	freeFlatNodes   []int32         // Indices in the flattened tree of nodes removed since it was flattened
+	changed         []*Node         // Nodes whose extents or children changed since the flattened tree was patched
+}
+
+// Insert adds a triangle to the BVH without rebuilding it and returns its ID, the index of the triangle in
+// the vertex array. The ID and bounding box slot of the last removed triangle are reused if there is one,
+// so the vertex array only grows when the BVH holds more triangles than ever before. The triangle gets a leaf
+// of its own, placed next to the node whose bounding box grows the least by the SAH (Catto's dynamic AABB tree),
+// after which the nodes above it are refitted and rotated to keep the tree balanced. Levels of the nodes moved
+// down are not updated.
+func (bvh *BVH) Insert(triangle Triangle) int {
+	dynamic := bvh.dynamicTree()
+
+	if !dynamic.ownsVertexArray {
+		vertexArray := make([]float64, len(bvh.vertexArray), len(bvh.vertexArray)+9)
+		copy(vertexArray, bvh.vertexArray)
+		bvh.vertexArray = vertexArray
+		dynamic.ownsVertexArray = true
+	}
+
+	var id int
+	if n := len(dynamic.freeIDs); n > 0 {
+		id = dynamic.freeIDs[n-1]
+		dynamic.freeIDs = dynamic.freeIDs[:n-1]
+		for i, vertex := range triangle {
+			copy(bvh.vertexArray[id*9+i*3:], []float64{vertex.X, vertex.Y, vertex.Z})
+		}
+	} else {
+		id = len(bvh.vertexArray) / 9
+		for _, vertex := range triangle {
+			bvh.vertexArray = append(bvh.vertexArray, vertex.X, vertex.Y, vertex.Z)
+		}
+	}
+
+	var pos int
+	if n := len(dynamic.freeBoxes); n > 0 {
+		pos = dynamic.freeBoxes[n-1]
+		dynamic.freeBoxes = dynamic.freeBoxes[:n-1]
+		bvh.bboxArray[pos*7] = float64(id)
+	} else {
+		pos = len(bvh.bboxArray) / 7
+		bvh.bboxArray = append(bvh.bboxArray, float64(id), 0, 0, 0, 0, 0, 0)
+		bvh.bboxHelper = append(bvh.bboxHelper, 0, 0, 0, 0, 0, 0, 0)
+	}
+	bvh.refitBox(pos)
+
+	extents := bvh.CalcExtents(pos, pos+1, bvh.options.Padding)
+	leaf := NewBVHNode(extents[0], extents[1], pos, pos+1, 0)
+	dynamic.leaves[id] = []*Node{leaf}
+
+	bvh.insertLeaf(leaf)
+	bvh.patchFlatTree()
+	return id
+}
+
+// Remove deletes the triangle with the given ID from the BVH and reports whether it was part of it.
+// Leaves left empty are removed together with their parent, whose place is taken by the sibling,
+// and the nodes above are refitted and rotated. The ID and the triangle's bounding box slots are freed
+// for the next Insert, and its vertices stay in the vertex array until then.
+func (bvh *BVH) Remove(id int) bool {
+	dynamic := bvh.dynamicTree()
+
+	leaves, ok := dynamic.leaves[id]
+	if !ok {
+		return false
+	}
+	delete(dynamic.leaves, id)
+
+	for _, leaf := range leaves {
+		for i := leaf.StartIndex; i < leaf.EndIndex; i++ {
+			if int(bvh.bboxArray[i*7]) == id {
+				CopyBox(bvh.bboxArray, leaf.EndIndex-1, bvh.bboxArray, i)
+				leaf.EndIndex--
+				dynamic.freeBoxes = append(dynamic.freeBoxes, leaf.EndIndex)
+				break
+			}
+		}
+
+		if leaf.ElementCount() > 0 || leaf == bvh.rootNode {
+			bvh.refitNode(leaf)
+			dynamic.changed = append(dynamic.changed, leaf)
+			bvh.refitAncestors(dynamic.parents[leaf])
+		} else {
+			bvh.removeLeaf(leaf)
+		}
+	}
+	dynamic.freeIDs = append(dynamic.freeIDs, id)
+
+	bvh.patchFlatTree()
+	return true
+}
+
+// dynamicTree returns the bookkeeping for edits, collecting the parents and leaves of the tree on first use
+func (bvh *BVH) dynamicTree() *dynamicTree {
+	if bvh.dynamic != nil {
+		return bvh.dynamic
+	}
+
+	dynamic := &dynamicTree{
+		parents: map[*Node]*Node{},
+		leaves:  map[int][]*Node{},
+	}
+	nodesToVisit := []*Node{bvh.rootNode}
+
+	for len(nodesToVisit) > 0 {
+		node := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if node.Node0 != nil {
+			dynamic.parents[node.Node0] = node
+			dynamic.parents[node.Node1] = node
+			nodesToVisit = append(nodesToVisit, node.Node1, node.Node0)
+			continue
+		}
+		for i := node.StartIndex; i < node.EndIndex; i++ {
+			triIndex := int(bvh.bboxArray[i*7])
+			dynamic.leaves[triIndex] = append(dynamic.leaves[triIndex], node)
+		}
+	}
+
+	// Flatten again on the next query to record the index of every node for patching
+	bvh.dynamic = dynamic
+	bvh.invalidateFlatTree()
+	return dynamic
+}
+
+// insertLeaf links a new leaf into the tree next to the best sibling found by descending from the root.
+// At every node the cost of pairing the leaf with it is compared with the cheapest cost reachable in its children,
+// including the growth inherited by the node itself.
+func (bvh *BVH) insertLeaf(leaf *Node) {
+	dynamic := bvh.dynamic
+	root := bvh.rootNode
+
+	if root.Node0 == nil && root.ElementCount() == 0 {
+		dynamic.freeFlatNode(root)
+		bvh.rootNode = leaf
+		return
+	}
+
+	sibling := root
+	for sibling.Node0 != nil {
+		combinedArea := unionSurfaceArea(sibling, leaf)
+		cost := 2 * combinedArea
+		inheritanceCost := 2 * (combinedArea - sibling.SurfaceArea())
+
+		cost0 := descendCost(sibling.Node0, leaf) + inheritanceCost
+		cost1 := descendCost(sibling.Node1, leaf) + inheritanceCost
+
+		if cost < cost0 && cost < cost1 {
+			break
+		}
+		if cost0 < cost1 {
+			sibling = sibling.Node0
+		} else {
+			sibling = sibling.Node1
+		}
+	}
+
+	oldParent := dynamic.parents[sibling]
+	newParent := NewBVHNode(sibling.ExtentsMin.Clone(), sibling.ExtentsMax.Clone(), -1, -1, sibling.Level)
+	newParent.Node0 = sibling
+	newParent.Node1 = leaf
+	dynamic.parents[sibling] = newParent
+	dynamic.parents[leaf] = newParent
+	leaf.Level = newParent.Level + 1
+	refitInnerNode(newParent)
+
+	if oldParent == nil {
+		bvh.rootNode = newParent
+		return
+	}
+
+	if oldParent.Node0 == sibling {
+		oldParent.Node0 = newParent
+	} else {
+		oldParent.Node1 = newParent
+	}
+	dynamic.parents[newParent] = oldParent
+	bvh.refitAncestors(oldParent)
+}
+
+// descendCost returns the cost of inserting leaf somewhere below node, at least the growth of node's surface area
+func descendCost(node, leaf *Node) float64 {
+	if node.Node0 == nil {
+		return unionSurfaceArea(node, leaf)
+	}
+	return unionSurfaceArea(node, leaf) - node.SurfaceArea()
+}
+
+// removeLeaf unlinks an empty leaf from the tree, replacing its parent with its sibling
+func (bvh *BVH) removeLeaf(leaf *Node) {
+	dynamic := bvh.dynamic
+
+	parent := dynamic.parents[leaf]
+	delete(dynamic.parents, leaf)
+	dynamic.freeFlatNode(leaf)
+	dynamic.freeFlatNode(parent)
+
+	sibling := parent.Node0
+	if sibling == leaf {
+		sibling = parent.Node1
+	}
+
+	grandparent := dynamic.parents[parent]
+	delete(dynamic.parents, parent)
+
+	if grandparent == nil {
+		bvh.rootNode = sibling
+		delete(dynamic.parents, sibling)
+		return
+	}
+
+	if grandparent.Node0 == parent {
+		grandparent.Node0 = sibling
+	} else {
+		grandparent.Node1 = sibling
+	}
+	dynamic.parents[sibling] = grandparent
+	bvh.refitAncestors(grandparent)
+}
+
+// refitAncestors walks from node up to the root, refitting every node to its children and rotating it
+func (bvh *BVH) refitAncestors(node *Node) {
+	for ; node != nil; node = bvh.dynamic.parents[node] {
+		refitInnerNode(node)
+		bvh.dynamic.changed = append(bvh.dynamic.changed, node)
+		bvh.rotateNode(node)
+	}
+}
@@ -0,0 +1,299 @@
+package bvhtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// checkDynamicTree fails the test if the bookkeeping of Insert and Remove disagrees with the tree,
+// or if a node does not enclose its children and triangles
+func checkDynamicTree(t *testing.T, bvh *BVH) {
+	t.Helper()
+
+	dynamic := bvh.dynamicTree()
+	if parent, ok := dynamic.parents[bvh.rootNode]; ok {
+		t.Fatalf("root node has parent %p", parent)
+	}
+
+	parentCount := 0
+	leaves := map[int][]*Node{}
+	nodesToVisit := []*Node{bvh.rootNode}
+
+	for len(nodesToVisit) > 0 {
+		node := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if node.Node0 != nil {
+			for _, child := range [2]*Node{node.Node0, node.Node1} {
+				if dynamic.parents[child] != node {
+					t.Fatalf("parent of node %p is %p, expected %p", child, dynamic.parents[child], node)
+				}
+				if !nodeEncloses(node, child.ExtentsMin, child.ExtentsMax) {
+					t.Fatalf("node %p does not enclose its child %p", node, child)
+				}
+				parentCount++
+			}
+			nodesToVisit = append(nodesToVisit, node.Node1, node.Node0)
+			continue
+		}
+
+		for i := node.StartIndex; i < node.EndIndex; i++ {
+			triIndex := int(bvh.bboxArray[i*7])
+			leaves[triIndex] = append(leaves[triIndex], node)
+			if !nodeEncloses(node, NewPoint(bvh.bboxArray[i*7+1:i*7+4]...), NewPoint(bvh.bboxArray[i*7+4:i*7+7]...)) {
+				t.Fatalf("leaf %p does not enclose triangle %d", node, triIndex)
+			}
+		}
+	}
+
+	if len(dynamic.parents) != parentCount {
+		t.Fatalf("%d parents recorded, expected %d", len(dynamic.parents), parentCount)
+	}
+	if len(dynamic.leaves) != len(leaves) {
+		t.Fatalf("leaves of %d triangles recorded, expected %d", len(dynamic.leaves), len(leaves))
+	}
+	for triIndex, nodes := range leaves {
+		if len(dynamic.leaves[triIndex]) != len(nodes) {
+			t.Fatalf("triangle %d recorded in %d leaves, expected %d", triIndex, len(dynamic.leaves[triIndex]), len(nodes))
+		}
+	}
+
+	checkFlatTree(t, bvh)
+}
+
+// checkFlatTree fails the test if the flattened tree, unless it is discarded, differs from the node tree
+func checkFlatTree(t *testing.T, bvh *BVH) {
+	t.Helper()
+
+	nodes, _ := bvh.flatNodes.Load().([]flatNode)
+	if nodes == nil {
+		return
+	}
+
+	type entry struct {
+		node  *Node
+		index int32
+	}
+	visited := map[int32]bool{}
+	nodesToVisit := []entry{{bvh.rootNode, 0}}
+
+	for len(nodesToVisit) > 0 {
+		e := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if e.index < 0 || int(e.index) >= len(nodes) || visited[e.index] {
+			t.Fatalf("flat node index %d is out of range or shared", e.index)
+		}
+		visited[e.index] = true
+
+		flat, expected := nodes[e.index], newFlatNode(e.node)
+		if flat.min != expected.min || flat.max != expected.max || flat.isLeaf() != (e.node.Node0 == nil) {
+			t.Fatalf("flat node %d is %+v, expected %+v", e.index, flat, expected)
+		}
+		if e.node.Node0 == nil {
+			if flat.offset != expected.offset || flat.count != expected.count {
+				t.Fatalf("flat leaf %d has boxes %d+%d, expected %d+%d", e.index, flat.offset, flat.count, expected.offset, expected.count)
+			}
+			continue
+		}
+
+		index0, index1 := flat.children()
+		nodesToVisit = append(nodesToVisit, entry{e.node.Node1, index1}, entry{e.node.Node0, index0})
+	}
+
+	// Every index is either used by a node or free for the next edit
+	if dynamic := bvh.dynamic; dynamic != nil && dynamic.flatIndices != nil {
+		if len(dynamic.flatIndices) != len(visited) || len(nodes) != len(visited)+len(dynamic.freeFlatNodes) {
+			t.Fatalf("flattened tree has %d nodes, %d recorded and %d free, expected %d recorded",
+				len(nodes), len(dynamic.flatIndices), len(dynamic.freeFlatNodes), len(visited))
+		}
+	}
+}
+
+// nodeEncloses checks if the extents of node contain the box given by boxMin and boxMax
+func nodeEncloses(node *Node, boxMin, boxMax Point) bool {
+	return node.ExtentsMin.X <= boxMin.X && node.ExtentsMin.Y <= boxMin.Y && node.ExtentsMin.Z <= boxMin.Z &&
+		node.ExtentsMax.X >= boxMax.X && node.ExtentsMax.Y >= boxMax.Y && node.ExtentsMax.Z >= boxMax.Z
+}
+
+// randomTriangle returns a random triangle with edges up to 10 inside a cube of side 100
+func randomTriangle(r *rand.Rand) Triangle {
+	x, y, z := r.Float64()*100-50, r.Float64()*100-50, r.Float64()*100-50
+	var triangle Triangle
+	for i := range triangle {
+		triangle[i] = NewPoint(x+r.Float64()*10, y+r.Float64()*10, z+r.Float64()*10)
+	}
+	return triangle
+}
+
+func TestInsertRemove(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	vertexArray := randomTriangles(200, 10, 2)
+	bvh := newSAHBVH(vertexArray)
+
+	var live, free []int
+	for i := 0; i < len(vertexArray)/9; i++ {
+		live = append(live, i)
+	}
+
+	for step := 0; step < 400; step++ {
+		switch op := r.Intn(10); {
+		case op < 4:
+			triangle := randomTriangle(r)
+			id := bvh.Insert(triangle)
+			if n := len(free); n > 0 {
+				if id != free[n-1] {
+					t.Fatalf("Insert returned ID %d, expected the last removed ID %d", id, free[n-1])
+				}
+				free = free[:n-1]
+			} else if id != len(bvh.VertexArray())/9-1 {
+				t.Fatalf("Insert returned ID %d for a vertex array of %d triangles", id, len(bvh.VertexArray())/9)
+			}
+			if stored := bvh.Triangle(id); *stored[0] != *triangle[0] || *stored[1] != *triangle[1] || *stored[2] != *triangle[2] {
+				t.Fatalf("triangle %d is %v, expected %v", id, stored, triangle)
+			}
+			live = append(live, id)
+
+		case op < 8:
+			if len(live) > 0 {
+				i := r.Intn(len(live))
+				id := live[i]
+				if !bvh.Remove(id) {
+					t.Fatalf("Remove(%d) = false for a triangle of the BVH", id)
+				}
+				live[i] = live[len(live)-1]
+				live = live[:len(live)-1]
+				free = append(free, id)
+			}
+			if len(free) > 0 {
+				if id := free[r.Intn(len(free))]; bvh.Remove(id) {
+					t.Fatalf("Remove(%d) = true for a removed triangle", id)
+				}
+			}
+
+		case op < 9:
+			bvh.Optimize(1)
+
+		default:
+			moved := append([]float64(nil), bvh.VertexArray()...)
+			for i := range moved {
+				moved[i] += r.Float64() - 0.5
+			}
+			if err := bvh.Refit(moved); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		checkDynamicTree(t, bvh)
+		if step%10 == 0 {
+			checkQueriesBruteForce(t, bvh, live, int64(step))
+		}
+	}
+}
+
+func TestRemoveAllAndReinsert(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	bvh := newSAHBVH(randomTriangles(50, 10, 2))
+	boxCount := len(bvh.bboxArray) / 7
+
+	for round := 0; round < 3; round++ {
+		for id := 0; id < len(bvh.VertexArray())/9; id++ {
+			bvh.Remove(id)
+		}
+		checkDynamicTree(t, bvh)
+		checkQueriesBruteForce(t, bvh, nil, 1)
+		if result := bvh.ClosestPoint(NewPoint(0, 0, 0), math.Inf(1)); result != nil {
+			t.Fatalf("ClosestPoint on an empty BVH = %+v, expected nil", result)
+		}
+
+		var live []int
+		for i := 0; i < 30; i++ {
+			live = append(live, bvh.Insert(randomTriangle(r)))
+		}
+		checkDynamicTree(t, bvh)
+		checkQueriesBruteForce(t, bvh, live, 2)
+
+		// The reinserted triangles take the IDs and bounding box slots of the removed ones
+		if len(bvh.VertexArray()) != 50*9 || len(bvh.bboxArray) != boxCount*7 {
+			t.Fatalf("round %d grew the arrays to %d triangles and %d bounding boxes, expected 50 and %d",
+				round, len(bvh.VertexArray())/9, len(bvh.bboxArray)/7, boxCount)
+		}
+	}
+}
+
+func TestEditsPatchFlatTree(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	vertexArray := randomTriangles(200, 10, 2)
+	bvh := newSAHBVH(vertexArray)
+
+	// The first edit sets up the bookkeeping and discards the flattened tree once
+	bvh.Remove(0)
+	bvh.flatTree()
+
+	var live []int
+	for i := 1; i < len(vertexArray)/9; i++ {
+		live = append(live, i)
+	}
+
+	for step := 0; step < 400; step++ {
+		if r.Intn(2) == 0 || len(live) == 0 {
+			live = append(live, bvh.Insert(randomTriangle(r)))
+		} else {
+			i := r.Intn(len(live))
+			bvh.Remove(live[i])
+			live[i] = live[len(live)-1]
+			live = live[:len(live)-1]
+		}
+
+		if nodes, _ := bvh.flatNodes.Load().([]flatNode); nodes == nil {
+			t.Fatalf("step %d discarded the flattened tree", step)
+		}
+		checkDynamicTree(t, bvh)
+		if step%20 == 0 {
+			checkQueriesBruteForce(t, bvh, live, int64(step))
+		}
+	}
+}
+
+func TestInsertLeavesVertexArrayAlone(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	// Spare capacity would let a careless append write into the caller's array
+	withSpareCapacity := func(values []float64) []float64 {
+		array := make([]float64, len(values), len(values)+90)
+		copy(array, values)
+		spare := array[len(values):cap(array)]
+		for i := range spare {
+			spare[i] = -1
+		}
+		return array
+	}
+	checkUnchanged := func(array, values []float64) {
+		t.Helper()
+		for i, v := range array[:cap(array)] {
+			if (i < len(values) && v != values[i]) || (i >= len(values) && v != -1) {
+				t.Fatalf("value %d of the caller's vertex array changed to %v", i, v)
+			}
+		}
+	}
+
+	vertexArray := randomTriangles(50, 10, 2)
+	built := withSpareCapacity(vertexArray)
+	bvh := newSAHBVH(built)
+	bvh.Insert(randomTriangle(r))
+	checkUnchanged(built, vertexArray)
+
+	// After Refit the BVH uses the caller's new array, which must be copied again before inserting
+	vertexArray = append([]float64(nil), bvh.VertexArray()...)
+	refitted := withSpareCapacity(vertexArray)
+	if err := bvh.Refit(refitted); err != nil {
+		t.Fatal(err)
+	}
+	bvh.Remove(3)
+	if id := bvh.Insert(randomTriangle(r)); id != 3 {
+		t.Fatalf("Insert returned ID %d, expected the removed ID 3", id)
+	}
+	checkUnchanged(refitted, vertexArray)
+	checkDynamicTree(t, bvh)
+}
@@ -0,0 +1,312 @@
+package bvhtree
+
+import "math"
+
+// flatNode is a node of the flattened tree, 32 bytes in size. flatten stores the nodes depth-first, but Insert and
+// Remove patch them in place afterwards, so an inner node refers to both of its children by index.
+type flatNode struct {
+	min, max [3]float32 // Bounds rounded outwards from the extents of the node
+	offset   int32      // First bounding box of a leaf, index of the second child of an inner node
+	count    int32      // Number of bounding boxes of a leaf, the complement of the first child's index for an inner node
+}
+
+// isLeaf reports whether the node is a leaf
+func (node *flatNode) isLeaf() bool {
+	return node.count >= 0
+}
+
+// children returns the indices of the first and second child of an inner node
+func (node *flatNode) children() (int32, int32) {
+	return ^node.count, node.offset
+}
+
+// bounds sets extentsMin and extentsMax to the bounds of the node
+func (node *flatNode) bounds(extentsMin, extentsMax Point) {
+	extentsMin.Set(float64(node.min[0]), float64(node.min[1]), float64(node.min[2]))
+	extentsMax.Set(float64(node.max[0]), float64(node.max[1]), float64(node.max[2]))
+}
+
+// surfaceArea returns the surface area of the node's bounds
+func (node *flatNode) surfaceArea() float64 {
+	return CalcSurfaceArea(
+		float64(node.max[0])-float64(node.min[0]),
+		float64(node.max[1])-float64(node.min[1]),
+		float64(node.max[2])-float64(node.min[2]),
+	)
+}
+
+// distanceSq returns the squared distance between p and the node's bounds, 0 if p is inside
+func (node *flatNode) distanceSq(p Point) float64 {
+	return axisDistanceSq(p.X, float64(node.min[0]), float64(node.max[0])) +
+		axisDistanceSq(p.Y, float64(node.min[1]), float64(node.max[1])) +
+		axisDistanceSq(p.Z, float64(node.min[2]), float64(node.max[2]))
+}
+
+// overlaps checks if the bounds of two flat nodes overlap, touching bounds count as overlapping
+func (node *flatNode) overlaps(other *flatNode) bool {
+	return node.min[0] <= other.max[0] && node.max[0] >= other.min[0] &&
+		node.min[1] <= other.max[1] && node.max[1] >= other.min[1] &&
+		node.min[2] <= other.max[2] && node.max[2] >= other.min[2]
+}
+
+// overlapsBox checks if the node's bounds overlap the box given by boxMin and boxMax, touching counts as overlapping
+func (node *flatNode) overlapsBox(boxMin, boxMax Point) bool {
+	return float64(node.min[0]) <= boxMax.X && float64(node.max[0]) >= boxMin.X &&
+		float64(node.min[1]) <= boxMax.Y && float64(node.max[1]) >= boxMin.Y &&
+		float64(node.min[2]) <= boxMax.Z && float64(node.max[2]) >= boxMin.Z
+}
+
+// newFlatNode returns the flat node for node, without the indices of its children if it is an inner node
+func newFlatNode(node *Node) flatNode {
+	flat := flatNode{
+		min: [3]float32{roundDown(node.ExtentsMin.X), roundDown(node.ExtentsMin.Y), roundDown(node.ExtentsMin.Z)},
+		max: [3]float32{roundUp(node.ExtentsMax.X), roundUp(node.ExtentsMax.Y), roundUp(node.ExtentsMax.Z)},
+	}
+	if node.Node0 == nil {
+		flat.offset = int32(node.StartIndex)
+		flat.count = int32(node.ElementCount())
+	}
+	return flat
+}
+
+// flatNodeDistance is the index of a flat node together with its distance to the query, the ray parameter at which
+// a ray enters its bounds or the squared distance between a point and them
+type flatNodeDistance struct {
+	index    int32
+	distance float64
+}
+
+// flatTree returns the flattened tree used by the queries, flattening the node tree first if it has changed.
+// Readers load the published slice without locking, only the first query after a change takes flatMutex.
+// Insert and Remove keep the flattened tree up to date instead of discarding it.
+//
+// The Node tree stays the representation the BVH is built, split, edited and optimized on, so both are kept in
+// memory. QueryFrustumFunc and NodeTriangles traverse the Node tree, since they hand out and take *Node values.
+func (bvh *BVH) flatTree() []flatNode {
+	if nodes, _ := bvh.flatNodes.Load().([]flatNode); nodes != nil {
+		return nodes
+	}
+
+	bvh.flatMutex.Lock()
+	defer bvh.flatMutex.Unlock()
+
+	nodes, _ := bvh.flatNodes.Load().([]flatNode)
+	if nodes == nil {
+		nodes = bvh.flatten()
+		bvh.flatNodes.Store(nodes)
+	}
+	return nodes
+}
+
+// invalidateFlatTree discards the flattened tree after the node tree has changed
+func (bvh *BVH) invalidateFlatTree() {
+	bvh.flatNodes.Store([]flatNode(nil))
+}
+
+// flatten lays out the node tree depth-first in a single slice. For a BVH that has been edited it also records
+// the index of every node, so that later edits can patch the slice.
+func (bvh *BVH) flatten() []flatNode {
+	// An entry's parent is the inner node whose offset points to it, -1 for the root and every first child
+	type flattenEntry struct {
+		node   *Node
+		parent int32
+	}
+
+	var nodes []flatNode
+	nodesToVisit := []flattenEntry{{bvh.rootNode, -1}}
+
+	dynamic := bvh.dynamic
+	if dynamic != nil {
+		dynamic.flatIndices = map[*Node]int32{}
+		dynamic.freeFlatNodes = nil
+		dynamic.changed = nil
+	}
+
+	for len(nodesToVisit) > 0 {
+		entry := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		index := int32(len(nodes))
+		if entry.parent >= 0 {
+			nodes[entry.parent].offset = index
+		}
+
+		node := entry.node
+		flat := newFlatNode(node)
+		if node.Node0 != nil {
+			flat.count = ^(index + 1)
+			nodesToVisit = append(nodesToVisit, flattenEntry{node.Node1, index}, flattenEntry{node.Node0, -1})
+		}
+		if dynamic != nil {
+			dynamic.flatIndices[node] = index
+		}
+
+		nodes = append(nodes, flat)
+	}
+
+	return nodes
+}
+
+// patchFlatTree updates the flattened tree in place after an edit, writing every node recorded as changed.
+// Nodes new to the flattened tree are written too, at the index of a removed node or appended. Nothing is done
+// while the flattened tree is discarded, the next query flattens the whole tree anyway.
+func (bvh *BVH) patchFlatTree() {
+	dynamic := bvh.dynamic
+	nodes, _ := bvh.flatNodes.Load().([]flatNode)
+	if nodes == nil {
+		dynamic.changed = dynamic.changed[:0]
+		return
+	}
+
+	for len(dynamic.changed) > 0 {
+		node := dynamic.changed[len(dynamic.changed)-1]
+		dynamic.changed = dynamic.changed[:len(dynamic.changed)-1]
+
+		// Skip nodes removed from the tree after they changed
+		if node != bvh.rootNode && dynamic.parents[node] == nil {
+			continue
+		}
+		nodes = bvh.writeFlatNode(nodes, node)
+	}
+
+	bvh.flatNodes.Store(nodes)
+}
+
+// writeFlatNode stores node at its index in the flattened tree, which grows if it or its children are new to it
+func (bvh *BVH) writeFlatNode(nodes []flatNode, node *Node) []flatNode {
+	nodes, index := bvh.flatIndex(nodes, node)
+
+	flat := newFlatNode(node)
+	if node.Node0 != nil {
+		var index0, index1 int32
+		nodes, index0 = bvh.flatIndex(nodes, node.Node0)
+		nodes, index1 = bvh.flatIndex(nodes, node.Node1)
+		flat.offset, flat.count = index1, ^index0
+	}
+
+	nodes[index] = flat
+	return nodes
+}
+
+// flatIndex returns the index of node in the flattened tree. Nodes new to it take the index of a removed node
+// or are appended, and are recorded as changed so that patchFlatTree writes them. The root is always stored
+// at index 0, where the traversals start, so a former root moves.
+func (bvh *BVH) flatIndex(nodes []flatNode, node *Node) ([]flatNode, int32) {
+	dynamic := bvh.dynamic
+	index, ok := dynamic.flatIndices[node]
+
+	if node == bvh.rootNode {
+		if ok && index == 0 {
+			return nodes, 0
+		}
+		if ok {
+			dynamic.freeFlatNodes = append(dynamic.freeFlatNodes, index)
+		}
+		dynamic.flatIndices[node] = 0
+		dynamic.changed = append(dynamic.changed, node)
+		return nodes, 0
+	}
+	if ok && index != 0 {
+		return nodes, index
+	}
+
+	if n := len(dynamic.freeFlatNodes); n > 0 {
+		index = dynamic.freeFlatNodes[n-1]
+		dynamic.freeFlatNodes = dynamic.freeFlatNodes[:n-1]
+	} else {
+		index = int32(len(nodes))
+		nodes = append(nodes, flatNode{})
+	}
+	dynamic.flatIndices[node] = index
+	dynamic.changed = append(dynamic.changed, node)
+	return nodes, index
+}
+
+// freeFlatNode releases the index of a node removed from the tree for reuse, except index 0 which stays with the root
+func (dynamic *dynamicTree) freeFlatNode(node *Node) {
+	index, ok := dynamic.flatIndices[node]
+	if !ok {
+		return
+	}
+	delete(dynamic.flatIndices, node)
+	if index != 0 {
+		dynamic.freeFlatNodes = append(dynamic.freeFlatNodes, index)
+	}
+}
+
+// roundDown converts x to the largest float32 not above it
+func roundDown(x float64) float32 {
+	f := float32(x)
+	if float64(f) > x {
+		f = math.Nextafter32(f, float32(math.Inf(-1)))
+	}
+	return f
+}
+
+// roundUp converts x to the smallest float32 not below it
+func roundUp(x float64) float32 {
+	f := float32(x)
+	if float64(f) < x {
+		f = math.Nextafter32(f, float32(math.Inf(1)))
+	}
+	return f
+}
+
+// flatRay holds a ray in the form used for testing it against flat nodes
+type flatRay struct {
+	origin   [3]float64
+	invDir   [3]float64
+	parallel [3]bool // The direction component is zero, so the ray never crosses the slab of the axis
+	invalid  bool    // The direction has a NaN component, so the ray misses every node
+}
+
+// newFlatRay prepares the ray from rayOrigin along rayDirection for testing against flat nodes
+func newFlatRay(rayOrigin, rayDirection Point) flatRay {
+	ray := flatRay{
+		origin: [3]float64{rayOrigin.X, rayOrigin.Y, rayOrigin.Z},
+		invDir: [3]float64{1.0 / rayDirection.X, 1.0 / rayDirection.Y, 1.0 / rayDirection.Z},
+	}
+	for axis, invdir := range ray.invDir {
+		ray.parallel[axis] = math.IsInf(invdir, 0)
+		ray.invalid = ray.invalid || math.IsNaN(invdir)
+	}
+	return ray
+}
+
+// intersectFlatNodeT checks if a ray intersects with a flat node's bounds between the ray parameters tmin and tmax
+// and returns the ray parameter at which it enters them. It gives the same results as intersectNodeBoxT
+// for the node the flat node was made from, apart from the outward rounding of the bounds.
+func intersectFlatNodeT(ray *flatRay, node *flatNode, tmin, tmax float64) (float64, bool) {
+	if ray.invalid {
+		return tmin, false
+	}
+
+	for axis := 0; axis < 3; axis++ {
+		minVal, maxVal := float64(node.min[axis]), float64(node.max[axis])
+		origin := ray.origin[axis]
+
+		if ray.parallel[axis] {
+			if origin < minVal || origin > maxVal || tmin > tmax {
+				return tmin, false
+			}
+			continue
+		}
+
+		t0 := (minVal - origin) * ray.invDir[axis]
+		t1 := (maxVal - origin) * ray.invDir[axis]
+		if ray.invDir[axis] < 0 {
+			t0, t1 = t1, t0
+		}
+		if t0 > tmin {
+			tmin = t0
+		}
+		if t1 < tmax {
+			tmax = t1
+		}
+		if tmin > tmax {
+			return tmin, false
+		}
+	}
+
+	return tmin, true
+}
@@ -0,0 +1,150 @@
+package bvhtree
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// mortonBits is the number of bits per axis of the 63-bit Morton codes used by SplitLBVH
+const mortonBits = 21
+
+// buildLBVH builds the tree below root as a linear BVH. The bounding boxes are sorted by the Morton codes
+// of their centroids, so every subtree covers a contiguous range of codes. Each node is split where the
+// highest bit still differing within its range flips, then the extents are computed bottom-up.
+func (bvh *BVH) buildLBVH(root *Node, progress *buildProgress) {
+	offset := root.StartIndex
+	codes := bvh.sortByMortonCode(root.StartIndex, root.EndIndex)
+
+	nodesToSplit := []*Node{root}
+
+	for len(nodesToSplit) > 0 {
+		node := nodesToSplit[len(nodesToSplit)-1]
+		nodesToSplit = nodesToSplit[:len(nodesToSplit)-1]
+
+		count := node.ElementCount()
+		if count <= bvh.options.MaxTrianglesPerNode || (bvh.options.MaxDepth > 0 && node.Level >= bvh.options.MaxDepth) {
+			progress.leafCreated(count)
+			continue
+		}
+
+		split := mortonSplit(codes, node.StartIndex-offset, node.EndIndex-offset) + offset
+
+		node.Node0 = NewBVHNode(&Vector3{}, &Vector3{}, node.StartIndex, split, node.Level+1)
+		node.Node1 = NewBVHNode(&Vector3{}, &Vector3{}, split, node.EndIndex, node.Level+1)
+		node.ClearShapes()
+
+		nodesToSplit = append(nodesToSplit, node.Node1, node.Node0)
+	}
+
+	bvh.refitNode(root)
+}
+
+// sortByMortonCode sorts the bounding boxes between startIndex and endIndex by the Morton codes of their centroids
+// and returns the sorted codes
+func (bvh *BVH) sortByMortonCode(startIndex, endIndex int) []uint64 {
+	count := endIndex - startIndex
+
+	centroidsMin := [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	centroidsMax := [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for i := startIndex; i < endIndex; i++ {
+		for axis := 0; axis < 3; axis++ {
+			c := bvh.centroid(i, axis)
+			centroidsMin[axis] = math.Min(centroidsMin[axis], c)
+			centroidsMax[axis] = math.Max(centroidsMax[axis], c)
+		}
+	}
+
+	var scale [3]float64
+	for axis := 0; axis < 3; axis++ {
+		if extent := centroidsMax[axis] - centroidsMin[axis]; extent > 0 {
+			scale[axis] = float64(1<<mortonBits-1) / extent
+		}
+	}
+
+	codes := make([]uint64, count)
+	order := make([]int32, count)
+	for i := range codes {
+		var code uint64
+		for axis := 0; axis < 3; axis++ {
+			cell := uint64((bvh.centroid(startIndex+i, axis) - centroidsMin[axis]) * scale[axis])
+			code |= expandMortonBits(cell) << uint(2-axis)
+		}
+		codes[i] = code
+		order[i] = int32(i)
+	}
+
+	codes, order = radixSort(codes, order)
+
+	for i, entry := range order {
+		CopyBox(bvh.bboxArray, startIndex+int(entry), bvh.bboxHelper, startIndex+i)
+	}
+	copy(bvh.bboxArray[startIndex*7:endIndex*7], bvh.bboxHelper[startIndex*7:endIndex*7])
+
+	return codes
+}
+
+// expandMortonBits spreads the lowest 21 bits of v so that two zero bits follow each of them
+func expandMortonBits(v uint64) uint64 {
+	v &= 0x1fffff
+	v = (v | v<<32) & 0x1f00000000ffff
+	v = (v | v<<16) & 0x1f0000ff0000ff
+	v = (v | v<<8) & 0x100f00f00f00f00f
+	v = (v | v<<4) & 0x10c30c30c30c30c3
+	v = (v | v<<2) & 0x1249249249249249
+	return v
+}
+
+// radixSort sorts keys in ascending order together with values, eight bits per pass.
+// Passes in which all keys share the same digit are skipped. The returned slices may be the input slices or new ones.
+func radixSort(keys []uint64, values []int32) ([]uint64, []int32) {
+	if len(keys) < 2 {
+		return keys, values
+	}
+
+	keysTemp := make([]uint64, len(keys))
+	valuesTemp := make([]int32, len(values))
+
+	for shift := uint(0); shift < 64; shift += 8 {
+		var counts [256]int
+		for _, key := range keys {
+			counts[(key>>shift)&0xff]++
+		}
+		if counts[(keys[0]>>shift)&0xff] == len(keys) {
+			continue
+		}
+
+		offset := 0
+		for digit, count := range counts {
+			counts[digit] = offset
+			offset += count
+		}
+
+		for i, key := range keys {
+			digit := (key >> shift) & 0xff
+			keysTemp[counts[digit]] = key
+			valuesTemp[counts[digit]] = values[i]
+			counts[digit]++
+		}
+
+		keys, keysTemp = keysTemp, keys
+		values, valuesTemp = valuesTemp, values
+	}
+
+	return keys, values
+}
+
+// mortonSplit returns the index at which the range of sorted codes between start and end is split:
+// the first code with the highest differing bit of the range set, or the middle if all codes are equal
+func mortonSplit(codes []uint64, start, end int) int {
+	first := codes[start]
+	last := codes[end-1]
+	if first == last {
+		return (start + end) / 2
+	}
+
+	prefix := bits.LeadingZeros64(first ^ last)
+	return start + sort.Search(end-start, func(i int) bool {
+		return bits.LeadingZeros64(first^codes[start+i]) <= prefix
+	})
+}
@@ -0,0 +1,145 @@
+package bvhtree
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newLBVH builds a BVH with the LBVH strategy and otherwise default options
+func newLBVH(vertexArray []float64) *BVH {
+	options := DefaultBuildOptions()
+	options.SplitStrategy = SplitLBVH
+	return NewBVHFromVertexArrayWithOptions(vertexArray, options)
+}
+
+func TestLBVHBruteForce(t *testing.T) {
+	vertexArray := clusteredTriangles(1)
+	bvh := newLBVH(vertexArray)
+
+	triangles := make([]int, len(vertexArray)/9)
+	for i := range triangles {
+		triangles[i] = i
+	}
+	checkSameTriangles(t, "NodeTriangles", bvh.NodeTriangles(bvh.rootNode, nil), triangles)
+	checkQueriesBruteForce(t, bvh, triangles, 2)
+}
+
+func TestLBVHEqualCodes(t *testing.T) {
+	// Every triangle has the same centroid, so all Morton codes are equal
+	var vertexArray []float64
+	for i := 0; i < 100; i++ {
+		vertexArray = append(vertexArray, 0, 0, 0, 1, 0, 0, 0, 1, 0)
+	}
+	bvh := newLBVH(vertexArray)
+
+	leaves := 0
+	nodesToVisit := []*Node{bvh.rootNode}
+	for len(nodesToVisit) > 0 {
+		node := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if node.Node0 != nil {
+			nodesToVisit = append(nodesToVisit, node.Node0, node.Node1)
+			continue
+		}
+		leaves++
+		if node.ElementCount() > bvh.options.MaxTrianglesPerNode {
+			t.Fatalf("leaf with %d triangles, expected at most %d", node.ElementCount(), bvh.options.MaxTrianglesPerNode)
+		}
+	}
+	if leaves < 100/bvh.options.MaxTrianglesPerNode {
+		t.Errorf("%d leaves for 100 triangles", leaves)
+	}
+
+	if results := bvh.IntersectRay(NewPoint(0.25, 0.25, 1), NewPoint(0, 0, -1), false); len(results) != 100 {
+		t.Errorf("%d intersections, expected 100", len(results))
+	}
+
+	codes := []uint64{7, 7, 7, 7, 7}
+	if split := mortonSplit(codes, 1, 5); split != 3 {
+		t.Errorf("mortonSplit of equal codes = %d, expected 3", split)
+	}
+}
+
+func TestMortonSplit(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		codes := make([]uint64, 2+r.Intn(50))
+		for j := range codes {
+			codes[j] = r.Uint64() >> uint(r.Intn(64))
+		}
+		sort.Slice(codes, func(a, b int) bool { return codes[a] < codes[b] })
+		if codes[0] == codes[len(codes)-1] {
+			continue
+		}
+
+		// The split is the first code with the highest bit differing between the first and the last code set
+		highestBit := 63
+		for (codes[0]^codes[len(codes)-1])>>uint(highestBit) == 0 {
+			highestBit--
+		}
+		expected := 0
+		for codes[expected]>>uint(highestBit)&1 == 0 {
+			expected++
+		}
+
+		if split := mortonSplit(codes, 0, len(codes)); split != expected {
+			t.Fatalf("mortonSplit(%v) = %d, expected %d", codes, split, expected)
+		}
+	}
+}
+
+func TestExpandMortonBits(t *testing.T) {
+	for bit := 0; bit < 21; bit++ {
+		if expanded := expandMortonBits(1 << uint(bit)); expanded != 1<<uint(3*bit) {
+			t.Errorf("expandMortonBits(1 << %d) = %#x, expected %#x", bit, expanded, uint64(1)<<uint(3*bit))
+		}
+	}
+	if expanded := expandMortonBits(1<<21 | 1); expanded != 1 {
+		t.Errorf("expandMortonBits kept bits above the lowest 21: %#x", expanded)
+	}
+}
+
+func TestRadixSort(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name string
+		key  func() uint64
+	}{
+		{"full width", r.Uint64},
+		// The upper passes are skipped since all keys share their digits there
+		{"small keys", func() uint64 { return r.Uint64() >> 44 }},
+		// The two lowest passes are skipped while the ones above them are not
+		{"shared low digits", func() uint64 { return r.Uint64()<<16 | 0xbeef }},
+		{"few distinct keys", func() uint64 { return uint64(r.Intn(4)) << 40 }},
+		{"equal keys", func() uint64 { return 42 }},
+	}
+
+	for _, test := range tests {
+		for _, count := range []int{0, 1, 2, 1000} {
+			keys := make([]uint64, count)
+			values := make([]int32, count)
+			for i := range keys {
+				keys[i] = test.key()
+				values[i] = int32(i)
+			}
+
+			// The radix sort is stable, so values of equal keys keep their order
+			expectedValues := make([]int32, count)
+			copy(expectedValues, values)
+			sort.SliceStable(expectedValues, func(a, b int) bool { return keys[expectedValues[a]] < keys[expectedValues[b]] })
+			expectedKeys := make([]uint64, count)
+			for i, value := range expectedValues {
+				expectedKeys[i] = keys[value]
+			}
+
+			sortedKeys, sortedValues := radixSort(keys, values)
+			if !reflect.DeepEqual(sortedKeys, expectedKeys) || !reflect.DeepEqual(sortedValues, expectedValues) {
+				t.Errorf("%s, %d keys: radixSort does not match sort.SliceStable", test.name, count)
+			}
+		}
+	}
+}
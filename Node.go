@@ -43,6 +43,15 @@ func (node *Node) CenterZ() float64 {
 	return (node.ExtentsMin.Z + node.ExtentsMax.Z) * 0.5
 }
 
+// SurfaceArea returns the surface area of the node's extents
+func (node *Node) SurfaceArea() float64 {
+	return CalcSurfaceArea(
+		node.ExtentsMax.X-node.ExtentsMin.X,
+		node.ExtentsMax.Y-node.ExtentsMin.Y,
+		node.ExtentsMax.Z-node.ExtentsMin.Z,
+	)
+}
+
 // ClearShapes clears the shapes in the node
 func (node *Node) ClearShapes() {
 	node.StartIndex = -1
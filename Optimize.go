@@ -0,0 +1,142 @@
+package bvhtree
+
+import "math"
+
+// Cost returns the SAH cost of the tree: the traversal cost of every inner node and the intersection cost of
+// every leaf triangle, weighted by the probability of a random ray hitting the node given that it hits the root
+func (bvh *BVH) Cost() float64 {
+	rootArea := bvh.rootNode.SurfaceArea()
+	if rootArea <= 0 {
+		rootArea = 1
+	}
+
+	cost := 0.0
+	nodesToVisit := []*Node{bvh.rootNode}
+
+	for len(nodesToVisit) > 0 {
+		node := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if node.Node0 != nil {
+			cost += bvh.options.SAHCost.Traversal * node.SurfaceArea() / rootArea
+			nodesToVisit = append(nodesToVisit, node.Node1, node.Node0)
+		} else {
+			cost += bvh.options.SAHCost.Intersection * float64(node.ElementCount()) * node.SurfaceArea() / rootArea
+		}
+	}
+
+	return cost
+}
+
+// Optimize lowers the SAH cost of the tree without rebuilding it and returns the cost before and after.
+// Every iteration visits the nodes bottom-up and applies the tree rotation (Kensler 2008) that shrinks the
+// surface area of a child the most, swapping it with the other child's children. It stops early once an
+// iteration finds nothing to improve. The triangles of the leaves stay untouched.
+func (bvh *BVH) Optimize(iterations int) (float64, float64) {
+	costBefore := bvh.Cost()
+
+	for i := 0; i < iterations; i++ {
+		if !bvh.rotateNodes() {
+			break
+		}
+	}
+
+	bvh.updateLevels()
+	bvh.invalidateFlatTree()
+	return costBefore, bvh.Cost()
+}
+
+// rotateNodes applies the best rotation to every inner node in post-order and reports whether any was applied
+func (bvh *BVH) rotateNodes() bool {
+	var postOrder []*Node
+	nodesToVisit := []*Node{bvh.rootNode}
+
+	for len(nodesToVisit) > 0 {
+		node := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if node.Node0 != nil {
+			postOrder = append(postOrder, node)
+			nodesToVisit = append(nodesToVisit, node.Node0, node.Node1)
+		}
+	}
+
+	rotated := false
+	for i := len(postOrder) - 1; i >= 0; i-- {
+		if bvh.rotateNode(postOrder[i]) {
+			rotated = true
+		}
+	}
+	return rotated
+}
+
+// rotateNode swaps one child of node with a grandchild below the other child if that reduces the surface area
+// of the other child, which is the only node whose extents change. It reports whether a rotation was applied.
+func (bvh *BVH) rotateNode(node *Node) bool {
+	if node.Node0 == nil {
+		return false
+	}
+
+	var bestParent *Node
+	var bestChild, bestGrandchild **Node
+	bestArea := 0.0
+
+	try := func(child **Node, parent *Node) {
+		if parent.Node0 == nil {
+			return
+		}
+
+		area := parent.SurfaceArea()
+		// Swapping child with one grandchild leaves parent with child and the other grandchild
+		if reduction := area - unionSurfaceArea(*child, parent.Node1); reduction > bestArea {
+			bestArea, bestParent, bestChild, bestGrandchild = reduction, parent, child, &parent.Node0
+		}
+		if reduction := area - unionSurfaceArea(*child, parent.Node0); reduction > bestArea {
+			bestArea, bestParent, bestChild, bestGrandchild = reduction, parent, child, &parent.Node1
+		}
+	}
+
+	try(&node.Node0, node.Node1)
+	try(&node.Node1, node.Node0)
+
+	// Ignore improvements that are only rounding noise
+	if bestParent == nil || bestArea <= EPSILON*node.SurfaceArea() {
+		return false
+	}
+
+	*bestChild, *bestGrandchild = *bestGrandchild, *bestChild
+	refitInnerNode(bestParent)
+
+	if bvh.dynamic != nil {
+		bvh.dynamic.parents[*bestChild] = node
+		bvh.dynamic.parents[*bestGrandchild] = bestParent
+		bvh.dynamic.changed = append(bvh.dynamic.changed, node, bestParent)
+	}
+	return true
+}
+
+// unionSurfaceArea returns the surface area of the box enclosing two nodes
+func unionSurfaceArea(node0, node1 *Node) float64 {
+	return CalcSurfaceArea(
+		math.Max(node0.ExtentsMax.X, node1.ExtentsMax.X)-math.Min(node0.ExtentsMin.X, node1.ExtentsMin.X),
+		math.Max(node0.ExtentsMax.Y, node1.ExtentsMax.Y)-math.Min(node0.ExtentsMin.Y, node1.ExtentsMin.Y),
+		math.Max(node0.ExtentsMax.Z, node1.ExtentsMax.Z)-math.Min(node0.ExtentsMin.Z, node1.ExtentsMin.Z),
+	)
+}
+
+// updateLevels sets the level of every node to its depth in the tree
+func (bvh *BVH) updateLevels() {
+	bvh.rootNode.Level = 0
+	nodesToVisit := []*Node{bvh.rootNode}
+
+	for len(nodesToVisit) > 0 {
+		node := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if node.Node0 != nil {
+			node.Node0.Level = node.Level + 1
+			node.Node1.Level = node.Level + 1
+			nodesToVisit = append(nodesToVisit, node.Node1, node.Node0)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package bvhtree
+
+import (
+	"math"
+	"testing"
+)
+
+// twisted returns a copy of the vertex array twisted around the Y axis by up to a full turn and stretched along X,
+// which moves neighbouring triangles far apart while keeping the tree built for the original positions
+func twisted(vertexArray []float64) []float64 {
+	result := make([]float64, len(vertexArray))
+	for i := 0; i < len(vertexArray); i += 3 {
+		x, y, z := vertexArray[i], vertexArray[i+1], vertexArray[i+2]
+		angle := (y + 50) / 100 * 2 * math.Pi
+		sin, cos := math.Sincos(angle)
+		result[i] = (x*cos - z*sin) * (1 + (z+50)/25)
+		result[i+1] = y
+		result[i+2] = x*sin + z*cos
+	}
+	return result
+}
+
+func TestOptimizeDegradedTree(t *testing.T) {
+	vertexArray := randomTriangles(3000, 5, 1)
+	triangles := make([]int, len(vertexArray)/9)
+	for i := range triangles {
+		triangles[i] = i
+	}
+
+	for _, strategy := range []SplitStrategy{SplitMidpoint, SplitBinnedSAH} {
+		options := DefaultBuildOptions()
+		options.SplitStrategy = strategy
+		bvh := NewBVHFromVertexArrayWithOptions(vertexArray, options)
+		if err := bvh.Refit(twisted(vertexArray)); err != nil {
+			t.Fatal(err)
+		}
+
+		degradedCost := bvh.Cost()
+		before, after := bvh.Optimize(10)
+		if before != degradedCost || after != bvh.Cost() {
+			t.Fatalf("strategy %d: Optimize returned costs %v and %v, the tree cost %v before and %v after",
+				strategy, before, after, degradedCost, bvh.Cost())
+		}
+		if after >= before {
+			t.Errorf("strategy %d: Optimize went from cost %v to %v, expected a lower cost", strategy, before, after)
+		}
+
+		// A second pass starts from the optimized tree and can't make it worse
+		if again, afterAgain := bvh.Optimize(10); again != after || afterAgain > after {
+			t.Errorf("strategy %d: second Optimize went from %v to %v, expected to start at %v and not to increase", strategy, again, afterAgain, after)
+		}
+
+		checkSameTriangles(t, "NodeTriangles", bvh.NodeTriangles(bvh.rootNode, nil), triangles)
+		checkQueriesBruteForce(t, bvh, triangles, 2)
+	}
+}
@@ -0,0 +1,157 @@
+package bvhtree
+
+import "math"
+
+// QueryAABB returns the indices of all triangles intersecting the axis-aligned box given by boxMin and boxMax.
+// If boundsOnly is set, triangles are reported as soon as their bounding box overlaps the query box,
+// which is cheaper but may include triangles that lie just outside of it.
+func (bvh *BVH) QueryAABB(boxMin, boxMax Point, boundsOnly bool) []int {
+	var triangles []int
+	bvh.QueryAABBFunc(boxMin, boxMax, boundsOnly, func(triIndex int) bool {
+		triangles = append(triangles, triIndex)
+		return true
+	})
+	return triangles
+}
+
+// QueryAABBFunc calls callback with the index of every triangle intersecting the axis-aligned box given by boxMin and boxMax,
+// see QueryAABB. The query stops early when callback returns false.
+func (bvh *BVH) QueryAABBFunc(boxMin, boxMax Point, boundsOnly bool, callback func(triIndex int) bool) {
+	nodes := bvh.flatTree()
+	nodesToVisit := []int32{0}
+	reported := bvh.newTriangleSet()
+
+	var a, b, c Vector3
+
+	for len(nodesToVisit) > 0 {
+		node := &nodes[nodesToVisit[len(nodesToVisit)-1]]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if !node.overlapsBox(boxMin, boxMax) {
+			continue
+		}
+
+		if !node.isLeaf() {
+			index0, index1 := node.children()
+			nodesToVisit = append(nodesToVisit, index1, index0)
+			continue
+		}
+
+		for i := int(node.offset); i < int(node.offset+node.count); i++ {
+			if !boxOverlapsArray(bvh.bboxArray, i, boxMin, boxMax) {
+				continue
+			}
+
+			triIndex := int(bvh.bboxArray[i*7])
+			if !boundsOnly {
+				a.SetFromArray(bvh.vertexArray, triIndex*9)
+				b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+				c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+				if !TriangleIntersectsBox(&a, &b, &c, boxMin, boxMax) {
+					continue
+				}
+			}
+
+			if !reported.add(triIndex) {
+				continue
+			}
+			if !callback(triIndex) {
+				return
+			}
+		}
+	}
+}
+
+// BoxesOverlap checks if two axis-aligned boxes overlap, touching boxes count as overlapping
+func BoxesOverlap(min0, max0, min1, max1 Point) bool {
+	return min0.X <= max1.X && max0.X >= min1.X &&
+		min0.Y <= max1.Y && max0.Y >= min1.Y &&
+		min0.Z <= max1.Z && max0.Z >= min1.Z
+}
+
+// boxOverlapsArray checks if the bounding box at pos in bboxArray overlaps the box given by boxMin and boxMax
+func boxOverlapsArray(bboxArray []float64, pos int, boxMin, boxMax Point) bool {
+	return bboxArray[pos*7+1] <= boxMax.X && bboxArray[pos*7+4] >= boxMin.X &&
+		bboxArray[pos*7+2] <= boxMax.Y && bboxArray[pos*7+5] >= boxMin.Y &&
+		bboxArray[pos*7+3] <= boxMax.Z && bboxArray[pos*7+6] >= boxMin.Z
+}
+
+// TriangleIntersectsBox checks if the triangle a, b, c intersects the axis-aligned box given by boxMin and boxMax.
+// It implements the separating axis test by Akenine-Möller, testing the three box normals,
+// the triangle normal and the nine cross products of the triangle edges with the box axes.
+func TriangleIntersectsBox(a, b, c, boxMin, boxMax Point) bool {
+	center := Vector3{
+		X: (boxMin.X + boxMax.X) * 0.5,
+		Y: (boxMin.Y + boxMax.Y) * 0.5,
+		Z: (boxMin.Z + boxMax.Z) * 0.5,
+	}
+	halfSize := [3]float64{
+		(boxMax.X - boxMin.X) * 0.5,
+		(boxMax.Y - boxMin.Y) * 0.5,
+		(boxMax.Z - boxMin.Z) * 0.5,
+	}
+
+	// Move the box to the origin
+	var v0, v1, v2 Vector3
+	v0.SubVectors(a, &center)
+	v1.SubVectors(b, &center)
+	v2.SubVectors(c, &center)
+	vertices := [3][3]float64{
+		{v0.X, v0.Y, v0.Z},
+		{v1.X, v1.Y, v1.Z},
+		{v2.X, v2.Y, v2.Z},
+	}
+
+	// Box normals, i.e. the bounding box of the triangle against the box
+	for axis := 0; axis < 3; axis++ {
+		minVal := math.Min(math.Min(vertices[0][axis], vertices[1][axis]), vertices[2][axis])
+		maxVal := math.Max(math.Max(vertices[0][axis], vertices[1][axis]), vertices[2][axis])
+		if minVal > halfSize[axis] || maxVal < -halfSize[axis] {
+			return false
+		}
+	}
+
+	// Cross products of the triangle edges with the box axes
+	edges := [3][3]float64{}
+	for i := 0; i < 3; i++ {
+		next := (i + 1) % 3
+		for axis := 0; axis < 3; axis++ {
+			edges[i][axis] = vertices[next][axis] - vertices[i][axis]
+		}
+	}
+
+	for _, edge := range edges {
+		for boxAxis := 0; boxAxis < 3; boxAxis++ {
+			// testAxis = boxAxis x edge
+			var testAxis [3]float64
+			u := (boxAxis + 1) % 3
+			w := (boxAxis + 2) % 3
+			testAxis[u] = -edge[w]
+			testAxis[w] = edge[u]
+
+			if separatedOnAxis(testAxis, vertices, halfSize) {
+				return false
+			}
+		}
+	}
+
+	// Triangle normal
+	var normal Vector3
+	normal.CrossVectors(v1.SubVectors(&v1, &v0), v2.SubVectors(&v2, &v0))
+
+	return !separatedOnAxis([3]float64{normal.X, normal.Y, normal.Z}, [3][3]float64{vertices[0], vertices[0], vertices[0]}, halfSize)
+}
+
+// separatedOnAxis checks if the projections of the vertices and of a box centered at the origin on testAxis are disjoint
+func separatedOnAxis(testAxis [3]float64, vertices [3][3]float64, halfSize [3]float64) bool {
+	minVal := math.Inf(1)
+	maxVal := math.Inf(-1)
+	for _, v := range vertices {
+		p := v[0]*testAxis[0] + v[1]*testAxis[1] + v[2]*testAxis[2]
+		minVal = math.Min(minVal, p)
+		maxVal = math.Max(maxVal, p)
+	}
+
+	radius := halfSize[0]*math.Abs(testAxis[0]) + halfSize[1]*math.Abs(testAxis[1]) + halfSize[2]*math.Abs(testAxis[2])
+	return minVal > radius || maxVal < -radius
+}
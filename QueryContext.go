@@ -0,0 +1,65 @@
+package bvhtree
+
+// QueryContext holds the buffers used by ray queries so they can be reused from one query to the next.
+// Once the buffers have grown large enough, queries through a reused QueryContext perform no heap allocations.
+// A QueryContext must not be used by more than one goroutine at a time.
+type QueryContext struct {
+	nodes         []int32
+	nodeDistances []flatNodeDistance
+	triangles     []int
+	results       []IntersectionResult
+	closest       IntersectionResult
+	vectors       vectorPool
+}
+
+// NewQueryContext creates an empty QueryContext
+func NewQueryContext() *QueryContext {
+	return &QueryContext{}
+}
+
+// IntersectRayContext returns all intersections of the ray between tmin and tmax like IntersectRayRange, reusing the buffers of ctx.
+// The returned slice and the points of its results belong to ctx and are only valid until ctx is used for the next query.
+func (bvh *BVH) IntersectRayContext(ctx *QueryContext, rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) []IntersectionResult {
+	ctx.vectors.reset()
+	ctx.results = bvh.intersectRay(ctx, &ctx.vectors, rayOrigin, rayDirection, tmin, tmax, backfaceCulling, ctx.results[:0])
+	return ctx.results
+}
+
+// IntersectRayClosestContext returns the intersection closest to tmin like IntersectRayClosestRange, reusing the buffers of ctx.
+// The returned result belongs to ctx and is only valid until ctx is used for the next query.
+func (bvh *BVH) IntersectRayClosestContext(ctx *QueryContext, rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) *IntersectionResult {
+	ctx.vectors.reset()
+	closestIndex, closestHit := bvh.intersectRayClosest(ctx, rayOrigin, rayDirection, tmin, tmax, backfaceCulling)
+	if closestIndex < 0 {
+		return nil
+	}
+
+	ctx.closest = bvh.newIntersectionResult(&ctx.vectors, closestIndex, rayOrigin, rayDirection, closestHit)
+	return &ctx.closest
+}
+
+// vectorPool hands out vectors from a reusable backing slice
+type vectorPool struct {
+	vectors []Vector3
+	used    int
+}
+
+// get returns a vector from the pool, or a newly allocated one if the pool is nil.
+// When the backing slice is exhausted a larger one replaces it; vectors handed out earlier keep pointing into the old slice.
+func (pool *vectorPool) get() *Vector3 {
+	if pool == nil {
+		return &Vector3{}
+	}
+	if pool.used == len(pool.vectors) {
+		pool.vectors = make([]Vector3, 2*len(pool.vectors)+16)
+		pool.used = 0
+	}
+	v := &pool.vectors[pool.used]
+	pool.used++
+	return v
+}
+
+// reset makes all vectors of the pool available again
+func (pool *vectorPool) reset() {
+	pool.used = 0
+}
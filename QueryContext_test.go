@@ -0,0 +1,79 @@
+package bvhtree
+
+import "testing"
+
+func TestQueryContextAllocations(t *testing.T) {
+	bvh := newSAHBVH(sphereMesh(64, 128, 40))
+	rays := randomRays(256, 1)
+	ctx := NewQueryContext()
+
+	// Let the buffers of ctx grow to their steady-state size
+	for _, ray := range rays {
+		bvh.IntersectRayContext(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+		bvh.IntersectRayClosestContext(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+	}
+
+	i := 0
+	nextRay := func() Ray {
+		i++
+		return rays[i%len(rays)]
+	}
+
+	queries := []struct {
+		name  string
+		query func()
+	}{
+		{"IntersectRayContext", func() {
+			ray := nextRay()
+			bvh.IntersectRayContext(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+		}},
+		{"IntersectRayClosestContext", func() {
+			ray := nextRay()
+			bvh.IntersectRayClosestContext(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+		}},
+		{"Occluded", func() {
+			ray := nextRay()
+			bvh.Occluded(ray.Origin, ray.Direction, ray.TMin, ray.TMax)
+		}},
+	}
+
+	for _, q := range queries {
+		if allocs := testing.AllocsPerRun(len(rays), q.query); allocs != 0 {
+			t.Errorf("%s: %v allocations per query, expected 0", q.name, allocs)
+		}
+	}
+}
+
+func BenchmarkIntersectRayContext(b *testing.B) {
+	bvh := newSAHBVH(sphereMesh(64, 128, 40))
+	rays := randomRays(256, 1)
+	ctx := NewQueryContext()
+
+	for _, ray := range rays {
+		bvh.IntersectRayContext(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ray := rays[i%len(rays)]
+		bvh.IntersectRayContext(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+	}
+}
+
+func BenchmarkIntersectRayClosestContext(b *testing.B) {
+	bvh := newSAHBVH(sphereMesh(64, 128, 40))
+	rays := randomRays(256, 1)
+	ctx := NewQueryContext()
+
+	for _, ray := range rays {
+		bvh.IntersectRayClosestContext(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ray := rays[i%len(rays)]
+		bvh.IntersectRayClosestContext(ctx, ray.Origin, ray.Direction, ray.TMin, ray.TMax, false)
+	}
+}
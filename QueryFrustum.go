@@ -0,0 +1,163 @@
+package bvhtree
+
+// Plane represents the plane of points p with Normal·p + Constant = 0.
+// Points on the side the normal points to have a positive distance.
+type Plane struct {
+	Normal   Point
+	Constant float64
+}
+
+// NewPlane creates a plane with the given normal passing through point
+func NewPlane(normal, point Point) Plane {
+	return Plane{
+		Normal:   normal,
+		Constant: -normal.Dot(point),
+	}
+}
+
+// DistanceToPoint returns the signed distance of p to the plane, scaled by the length of the normal
+func (plane Plane) DistanceToPoint(p Point) float64 {
+	return plane.Normal.Dot(p) + plane.Constant
+}
+
+// FrustumClass is the classification of a bounding box against a frustum
+type FrustumClass int
+
+const (
+	// FrustumOutside means the box is entirely outside the frustum
+	FrustumOutside FrustumClass = iota
+	// FrustumIntersecting means the box crosses the frustum boundary
+	FrustumIntersecting
+	// FrustumInside means the box is entirely inside the frustum
+	FrustumInside
+)
+
+// FrustumResult holds the parts of a BVH found inside a frustum.
+// With SplitSpatialSAH, a triangle listed in Triangles may also belong to one of the Nodes.
+type FrustumResult struct {
+	Nodes     []*Node // Subtrees entirely inside the frustum, all of their triangles are visible
+	Triangles []int   // Triangles of leaves crossing the frustum boundary that are not entirely outside of it
+}
+
+// QueryFrustum finds the triangles inside the frustum bounded by planes, whose normals point into the frustum.
+// Subtrees entirely inside are reported as whole nodes instead of triangle by triangle.
+// Triangles of leaves crossing the boundary are kept unless all their vertices lie outside the same plane.
+func (bvh *BVH) QueryFrustum(planes []Plane) FrustumResult {
+	result := FrustumResult{}
+	reported := bvh.newTriangleSet()
+
+	bvh.QueryFrustumFunc(planes, func(node *Node, class FrustumClass) bool {
+		if class == FrustumInside {
+			result.Nodes = append(result.Nodes, node)
+			return false
+		}
+		if node.Node0 == nil {
+			result.Triangles = bvh.appendTrianglesInFrustum(result.Triangles, node, planes, reported)
+		}
+		return true
+	})
+
+	return result
+}
+
+// QueryFrustumFunc classifies the nodes of the BVH against the frustum bounded by planes, top-down.
+// callback is called for every node that is not outside the frustum and decides whether its children are visited.
+// Children of nodes outside the frustum are never visited.
+func (bvh *BVH) QueryFrustumFunc(planes []Plane, callback func(node *Node, class FrustumClass) bool) {
+	nodesToVisit := []*Node{bvh.rootNode}
+
+	for len(nodesToVisit) > 0 {
+		node := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		class := ClassifyBox(node.ExtentsMin, node.ExtentsMax, planes)
+		if class == FrustumOutside {
+			continue
+		}
+
+		if callback(node, class) && node.Node0 != nil {
+			nodesToVisit = append(nodesToVisit, node.Node1, node.Node0)
+		}
+	}
+}
+
+// ClassifyBox classifies the axis-aligned box given by extentsMin and extentsMax against the frustum bounded by planes.
+// For every plane, the box corner farthest along the normal decides whether the box is outside,
+// and the opposite corner whether it is crossing the plane.
+func ClassifyBox(extentsMin, extentsMax Point, planes []Plane) FrustumClass {
+	class := FrustumInside
+
+	for _, plane := range planes {
+		var positive, negative Vector3
+		positive.X, negative.X = extentsMin.X, extentsMax.X
+		if plane.Normal.X >= 0 {
+			positive.X, negative.X = extentsMax.X, extentsMin.X
+		}
+		positive.Y, negative.Y = extentsMin.Y, extentsMax.Y
+		if plane.Normal.Y >= 0 {
+			positive.Y, negative.Y = extentsMax.Y, extentsMin.Y
+		}
+		positive.Z, negative.Z = extentsMin.Z, extentsMax.Z
+		if plane.Normal.Z >= 0 {
+			positive.Z, negative.Z = extentsMax.Z, extentsMin.Z
+		}
+
+		if plane.DistanceToPoint(&positive) < 0 {
+			return FrustumOutside
+		}
+		if plane.DistanceToPoint(&negative) < 0 {
+			class = FrustumIntersecting
+		}
+	}
+
+	return class
+}
+
+// NodeTriangles appends the indices of all triangles in the subtree of node to triangles, each of them once
+func (bvh *BVH) NodeTriangles(node *Node, triangles []int) []int {
+	nodesToVisit := []*Node{node}
+	reported := bvh.newTriangleSet()
+
+	for len(nodesToVisit) > 0 {
+		node := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if node.Node0 != nil {
+			nodesToVisit = append(nodesToVisit, node.Node1, node.Node0)
+			continue
+		}
+		for i := node.StartIndex; i < node.EndIndex; i++ {
+			if triIndex := int(bvh.bboxArray[i*7]); reported.add(triIndex) {
+				triangles = append(triangles, triIndex)
+			}
+		}
+	}
+
+	return triangles
+}
+
+// appendTrianglesInFrustum appends the triangles of a leaf node that are not entirely outside one of the planes
+// and have not been reported yet
+func (bvh *BVH) appendTrianglesInFrustum(triangles []int, node *Node, planes []Plane, reported triangleSet) []int {
+	var a, b, c Vector3
+
+	for i := node.StartIndex; i < node.EndIndex; i++ {
+		triIndex := int(bvh.bboxArray[i*7])
+		a.SetFromArray(bvh.vertexArray, triIndex*9)
+		b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+		c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+		outside := false
+		for _, plane := range planes {
+			if plane.DistanceToPoint(&a) < 0 && plane.DistanceToPoint(&b) < 0 && plane.DistanceToPoint(&c) < 0 {
+				outside = true
+				break
+			}
+		}
+		if !outside && reported.add(triIndex) {
+			triangles = append(triangles, triIndex)
+		}
+	}
+
+	return triangles
+}
@@ -0,0 +1,94 @@
+package bvhtree
+
+// SphereContact represents a triangle within the radius of a sphere query
+type SphereContact struct {
+	TriangleIndex int
+	Point         Point   // Point of the triangle closest to the sphere center
+	DistanceSq    float64 // Squared distance between the sphere center and Point
+}
+
+// QuerySphere returns all triangles within radius of center together with their points closest to center
+func (bvh *BVH) QuerySphere(center Point, radius float64) []SphereContact {
+	var contacts []SphereContact
+	bvh.QuerySphereFunc(center, radius, func(contact SphereContact) bool {
+		contacts = append(contacts, contact)
+		return true
+	})
+	return contacts
+}
+
+// QuerySphereFunc calls callback for every triangle within radius of center, see QuerySphere.
+// The query stops early when callback returns false.
+func (bvh *BVH) QuerySphereFunc(center Point, radius float64, callback func(contact SphereContact) bool) {
+	if radius < 0 {
+		return
+	}
+
+	radiusSq := radius * radius
+	nodes := bvh.flatTree()
+	nodesToVisit := []int32{0}
+	reported := bvh.newTriangleSet()
+
+	var a, b, c, point Vector3
+
+	for len(nodesToVisit) > 0 {
+		node := &nodes[nodesToVisit[len(nodesToVisit)-1]]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		if !nodeOverlapsSphere(node, center, radius, radiusSq) {
+			continue
+		}
+
+		if !node.isLeaf() {
+			index0, index1 := node.children()
+			nodesToVisit = append(nodesToVisit, index1, index0)
+			continue
+		}
+
+		for i := int(node.offset); i < int(node.offset+node.count); i++ {
+			if distanceSqToArrayBox(center, bvh.bboxArray, i) > radiusSq {
+				continue
+			}
+
+			triIndex := int(bvh.bboxArray[i*7])
+			a.SetFromArray(bvh.vertexArray, triIndex*9)
+			b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+			c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+			closestPointOnTriangle(center, &a, &b, &c, &point)
+			dx, dy, dz := point.X-center.X, point.Y-center.Y, point.Z-center.Z
+			distanceSq := dx*dx + dy*dy + dz*dz
+			if distanceSq > radiusSq || !reported.add(triIndex) {
+				continue
+			}
+
+			if !callback(SphereContact{TriangleIndex: triIndex, Point: point.Clone(), DistanceSq: distanceSq}) {
+				return
+			}
+		}
+	}
+}
+
+// nodeOverlapsSphere checks if a flat node's bounds overlap a sphere. The bounding sphere of the node
+// gives a cheap early rejection before the exact box distance is computed.
+func nodeOverlapsSphere(node *flatNode, center Point, radius, radiusSq float64) bool {
+	var extentsMin, extentsMax Vector3
+	node.bounds(&extentsMin, &extentsMax)
+
+	dx := center.X - (extentsMin.X+extentsMax.X)*0.5
+	dy := center.Y - (extentsMin.Y+extentsMax.Y)*0.5
+	dz := center.Z - (extentsMin.Z+extentsMax.Z)*0.5
+	reach := radius + CalcBoundingSphereRadius(&extentsMin, &extentsMax)
+	if dx*dx+dy*dy+dz*dz > reach*reach {
+		return false
+	}
+
+	return node.distanceSq(center) <= radiusSq
+}
+
+// distanceSqToArrayBox returns the squared distance between p and the bounding box at pos in bboxArray, 0 if p is inside
+func distanceSqToArrayBox(p Point, bboxArray []float64, pos int) float64 {
+	return axisDistanceSq(p.X, bboxArray[pos*7+1], bboxArray[pos*7+4]) +
+		axisDistanceSq(p.Y, bboxArray[pos*7+2], bboxArray[pos*7+5]) +
+		axisDistanceSq(p.Z, bboxArray[pos*7+3], bboxArray[pos*7+6])
+}
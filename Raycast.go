@@ -0,0 +1,146 @@
+package bvhtree
+
+import "math"
+
+// Ray is a ray limited to the ray parameters between TMin and TMax
+type Ray struct {
+	Origin, Direction Point
+	TMin, TMax        float64
+}
+
+// NewRay creates a ray covering all ray parameters from 0 to infinity
+func NewRay(origin, direction Point) Ray {
+	return Ray{
+		Origin:    origin,
+		Direction: direction,
+		TMin:      0,
+		TMax:      math.Inf(1),
+	}
+}
+
+// IntersectRayClosest returns the intersection closest to the ray origin, or nil if the ray hits no triangle.
+// Nodes are visited front-to-back and nodes behind the closest intersection found so far are skipped.
+func (bvh *BVH) IntersectRayClosest(rayOrigin, rayDirection Point, backfaceCulling bool) *IntersectionResult {
+	return bvh.IntersectRayClosestRange(rayOrigin, rayDirection, 0, math.Inf(1), backfaceCulling)
+}
+
+// IntersectRayClosestRange returns the intersection between the ray parameters tmin and tmax closest to tmin,
+// or nil if the ray hits no triangle in that range.
+func (bvh *BVH) IntersectRayClosestRange(rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) *IntersectionResult {
+	closestIndex, closestHit := bvh.intersectRayClosest(NewQueryContext(), rayOrigin, rayDirection, tmin, tmax, backfaceCulling)
+	if closestIndex < 0 {
+		return nil
+	}
+
+	result := bvh.newIntersectionResult(nil, closestIndex, rayOrigin, rayDirection, closestHit)
+	return &result
+}
+
+// intersectRayClosest returns the index of the triangle closest to tmin hit by the ray, or -1 if there is none,
+// using the buffers in ctx for the traversal
+func (bvh *BVH) intersectRayClosest(ctx *QueryContext, rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) (int, triangleHit) {
+	ray := newFlatRay(rayOrigin, rayDirection)
+
+	closestDistance := tmax
+	closestIndex := -1
+	var closestHit triangleHit
+
+	nodes := bvh.flatTree()
+	rootDistance, ok := intersectFlatNodeT(&ray, &nodes[0], tmin, tmax)
+	if !ok {
+		return closestIndex, closestHit
+	}
+
+	nodesToIntersect := append(ctx.nodeDistances[:0], flatNodeDistance{0, rootDistance})
+
+	a := &Vector3{}
+	b := &Vector3{}
+	c := &Vector3{}
+
+	for len(nodesToIntersect) > 0 {
+		entry := nodesToIntersect[len(nodesToIntersect)-1]
+		nodesToIntersect = nodesToIntersect[:len(nodesToIntersect)-1]
+
+		if entry.distance > closestDistance {
+			continue
+		}
+
+		node := &nodes[entry.index]
+		if node.isLeaf() {
+			for i := node.offset; i < node.offset+node.count; i++ {
+				triIndex := int(bvh.bboxArray[i*7])
+				a.SetFromArray(bvh.vertexArray, triIndex*9)
+				b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+				c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+				if hit, ok := bvh.intersectTriangle(a, b, c, rayOrigin, rayDirection, tmin, closestDistance, backfaceCulling); ok && (closestIndex < 0 || hit.t < closestDistance) {
+					closestDistance = hit.t
+					closestIndex = triIndex
+					closestHit = hit
+				}
+			}
+			continue
+		}
+
+		index0, index1 := node.children()
+		t0, hit0 := intersectFlatNodeT(&ray, &nodes[index0], tmin, closestDistance)
+		t1, hit1 := intersectFlatNodeT(&ray, &nodes[index1], tmin, closestDistance)
+
+		// Push the farther child first so the nearer one is visited next
+		if hit0 && hit1 {
+			if t0 <= t1 {
+				nodesToIntersect = append(nodesToIntersect, flatNodeDistance{index1, t1}, flatNodeDistance{index0, t0})
+			} else {
+				nodesToIntersect = append(nodesToIntersect, flatNodeDistance{index0, t0}, flatNodeDistance{index1, t1})
+			}
+		} else if hit0 {
+			nodesToIntersect = append(nodesToIntersect, flatNodeDistance{index0, t0})
+		} else if hit1 {
+			nodesToIntersect = append(nodesToIntersect, flatNodeDistance{index1, t1})
+		}
+	}
+
+	ctx.nodeDistances = nodesToIntersect
+	return closestIndex, closestHit
+}
+
+// Occluded reports whether any triangle intersects the ray between the ray parameters tmin and tmax.
+// It stops at the first intersection found and does not allocate results, which makes it suited for shadow and visibility rays.
+func (bvh *BVH) Occluded(rayOrigin, rayDirection Point, tmin, tmax float64) bool {
+	ray := newFlatRay(rayOrigin, rayDirection)
+
+	nodes := bvh.flatTree()
+	var stackBuffer [64]int32
+	nodesToIntersect := append(stackBuffer[:0], 0)
+
+	var a, b, c Vector3
+
+	for len(nodesToIntersect) > 0 {
+		index := nodesToIntersect[len(nodesToIntersect)-1]
+		nodesToIntersect = nodesToIntersect[:len(nodesToIntersect)-1]
+
+		node := &nodes[index]
+		if _, ok := intersectFlatNodeT(&ray, node, tmin, tmax); !ok {
+			continue
+		}
+
+		if !node.isLeaf() {
+			index0, index1 := node.children()
+			nodesToIntersect = append(nodesToIntersect, index0, index1)
+			continue
+		}
+
+		for i := node.offset; i < node.offset+node.count; i++ {
+			triIndex := int(bvh.bboxArray[i*7])
+			a.SetFromArray(bvh.vertexArray, triIndex*9)
+			b.SetFromArray(bvh.vertexArray, triIndex*9+3)
+			c.SetFromArray(bvh.vertexArray, triIndex*9+6)
+
+			if _, ok := bvh.intersectTriangle(&a, &b, &c, rayOrigin, rayDirection, tmin, tmax, false); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
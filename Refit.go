@@ -0,0 +1,75 @@
+package bvhtree
+
+import (
+	"fmt"
+	"math"
+)
+
+// Refit updates the BVH for new vertex positions without rebuilding it.
+// The vertex array must contain the same triangles in the same order as the one the BVH was built from.
+// The per-triangle bounding boxes are recomputed and the node extents are propagated bottom-up,
+// keeping the tree structure and triangle order intact. Bounding boxes clipped by SplitSpatialSAH grow back
+// to the boxes of their whole triangles. The vertices of removed triangles whose IDs have not been reused yet
+// only hold the vertex array at its length, their values are ignored.
+func (bvh *BVH) Refit(vertexArray []float64) error {
+	if len(vertexArray) != len(bvh.vertexArray) {
+		return fmt.Errorf("bvhtree: refit vertex array has %d values, expected %d", len(vertexArray), len(bvh.vertexArray))
+	}
+
+	bvh.vertexArray = vertexArray
+	if bvh.dynamic != nil {
+		bvh.dynamic.ownsVertexArray = false
+	}
+
+	boxCount := len(bvh.bboxArray) / 7
+	for pos := 0; pos < boxCount; pos++ {
+		bvh.refitBox(pos)
+	}
+
+	bvh.refitNode(bvh.rootNode)
+	bvh.invalidateFlatTree()
+	return nil
+}
+
+// refitBox recalculates the bounding box at pos in the bboxArray from the triangle it refers to
+func (bvh *BVH) refitBox(pos int) {
+	triIndex := int(bvh.bboxArray[pos*7])
+	v := bvh.vertexArray[triIndex*9 : triIndex*9+9]
+
+	SetBox(bvh.bboxArray, pos, triIndex,
+		math.Min(math.Min(v[0], v[3]), v[6]),
+		math.Min(math.Min(v[1], v[4]), v[7]),
+		math.Min(math.Min(v[2], v[5]), v[8]),
+		math.Max(math.Max(v[0], v[3]), v[6]),
+		math.Max(math.Max(v[1], v[4]), v[7]),
+		math.Max(math.Max(v[2], v[5]), v[8]),
+	)
+}
+
+// refitNode recalculates the extents of a node after the extents of its children or its bounding boxes changed
+func (bvh *BVH) refitNode(node *Node) {
+	if node.Node0 == nil {
+		extents := bvh.CalcExtents(node.StartIndex, node.EndIndex, bvh.options.Padding)
+		node.ExtentsMin.Copy(extents[0])
+		node.ExtentsMax.Copy(extents[1])
+		return
+	}
+
+	bvh.refitNode(node.Node0)
+	bvh.refitNode(node.Node1)
+	refitInnerNode(node)
+}
+
+// refitInnerNode sets the extents of an inner node to the union of its children's extents
+func refitInnerNode(node *Node) {
+	node.ExtentsMin.Set(
+		math.Min(node.Node0.ExtentsMin.X, node.Node1.ExtentsMin.X),
+		math.Min(node.Node0.ExtentsMin.Y, node.Node1.ExtentsMin.Y),
+		math.Min(node.Node0.ExtentsMin.Z, node.Node1.ExtentsMin.Z),
+	)
+	node.ExtentsMax.Set(
+		math.Max(node.Node0.ExtentsMax.X, node.Node1.ExtentsMax.X),
+		math.Max(node.Node0.ExtentsMax.Y, node.Node1.ExtentsMax.Y),
+		math.Max(node.Node0.ExtentsMax.Z, node.Node1.ExtentsMax.Z),
+	)
+}
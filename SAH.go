@@ -0,0 +1,221 @@
+package bvhtree
+
+import (
+	"math"
+	"sort"
+)
+
+// SplitStrategy selects how SplitNode partitions the triangles of a node
+type SplitStrategy int
+
+const (
+	// SplitMidpoint splits at the spatial center of the node's extents
+	SplitMidpoint SplitStrategy = iota
+	// SplitSAH evaluates every split position between the sorted triangle centroids with the Surface Area Heuristic
+	SplitSAH
+	// SplitBinnedSAH evaluates the Surface Area Heuristic on a fixed number of equally sized centroid bins
+	SplitBinnedSAH
+	// SplitLBVH sorts all triangles along a Morton curve once and splits every node where the codes first differ.
+	// It builds much faster than the other strategies at the cost of tree quality. SplitNode itself falls back to SplitMidpoint.
+	SplitLBVH
+	// SplitSpatialSAH builds a spatial split BVH: triangles may be clipped and referenced from several leaves where
+	// that reduces the overlap between nodes, see BuildOptions.SpatialSplitOverlap. SplitNode itself falls back to SplitMidpoint.
+	SplitSpatialSAH
+)
+
+// SAHCost holds the constants used by the Surface Area Heuristic
+type SAHCost struct {
+	Traversal    float64 // Cost of traversing an inner node
+	Intersection float64 // Cost of intersecting a single triangle
+	Bins         int     // Number of bins per axis used by SplitBinnedSAH
+}
+
+// DefaultSAHCost returns the cost constants used when none are specified
+func DefaultSAHCost() SAHCost {
+	return SAHCost{
+		Traversal:    1.0,
+		Intersection: 1.0,
+		Bins:         16,
+	}
+}
+
+// partitionSAH finds the split with the lowest SAH cost among all centroid-sorted split positions on all three axes
+func (bvh *BVH) partitionSAH(node *Node) ([]int, []int, bool) {
+	count := node.ElementCount()
+	if count < 2 {
+		return nil, nil, false
+	}
+
+	parentArea := node.SurfaceArea()
+	if parentArea <= 0 {
+		parentArea = 1
+	}
+
+	orders := [3][]int{}
+	rightAreas := make([]float64, count)
+	bestCost := math.Inf(1)
+	bestAxis := -1
+	bestSplit := 0
+
+	for axis := 0; axis < 3; axis++ {
+		order := make([]int, count)
+		for i := range order {
+			order[i] = node.StartIndex + i
+		}
+		sort.Slice(order, func(i, j int) bool {
+			return bvh.centroid(order[i], axis) < bvh.centroid(order[j], axis)
+		})
+		orders[axis] = order
+
+		box := emptyBox()
+		for i := count - 1; i > 0; i-- {
+			box.expandByArray(bvh.bboxArray, order[i])
+			rightAreas[i] = box.SurfaceArea()
+		}
+
+		box = emptyBox()
+		for i := 1; i < count; i++ {
+			box.expandByArray(bvh.bboxArray, order[i-1])
+			cost := bvh.splitCost(i, box.SurfaceArea(), count-i, rightAreas[i], parentArea)
+			if cost < bestCost {
+				bestCost = cost
+				bestAxis = axis
+				bestSplit = i
+			}
+		}
+	}
+
+	if bestAxis < 0 || !bvh.shouldSplit(count, bestCost) {
+		return nil, nil, false
+	}
+
+	return orders[bestAxis][:bestSplit], orders[bestAxis][bestSplit:], true
+}
+
+// partitionBinnedSAH approximates partitionSAH by grouping the triangle centroids into equally sized bins
+func (bvh *BVH) partitionBinnedSAH(node *Node) ([]int, []int, bool) {
+	count := node.ElementCount()
+	if count < 2 {
+		return nil, nil, false
+	}
+
+	bins := bvh.options.SAHCost.Bins
+	if bins < 2 {
+		bins = 2
+	}
+
+	parentArea := node.SurfaceArea()
+	if parentArea <= 0 {
+		parentArea = 1
+	}
+
+	centroidMin := [3]float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	centroidMax := [3]float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for i := node.StartIndex; i < node.EndIndex; i++ {
+		for axis := 0; axis < 3; axis++ {
+			c := bvh.centroid(i, axis)
+			centroidMin[axis] = math.Min(centroidMin[axis], c)
+			centroidMax[axis] = math.Max(centroidMax[axis], c)
+		}
+	}
+
+	binBoxes := make([]BoundingBox, bins)
+	binCounts := make([]int, bins)
+	rightAreas := make([]float64, bins)
+	rightCounts := make([]int, bins)
+	bestCost := math.Inf(1)
+	bestAxis := -1
+	bestSplit := 0
+	bestScale := 0.0
+
+	for axis := 0; axis < 3; axis++ {
+		extent := centroidMax[axis] - centroidMin[axis]
+		if extent <= 0 {
+			continue
+		}
+		scale := float64(bins) / extent
+
+		for b := 0; b < bins; b++ {
+			binBoxes[b] = emptyBox()
+			binCounts[b] = 0
+		}
+		for i := node.StartIndex; i < node.EndIndex; i++ {
+			b := binIndex(bvh.centroid(i, axis), centroidMin[axis], scale, bins)
+			binBoxes[b].expandByArray(bvh.bboxArray, i)
+			binCounts[b]++
+		}
+
+		box := emptyBox()
+		rightCount := 0
+		for b := bins - 1; b > 0; b-- {
+			box.expandByBox(&binBoxes[b])
+			rightCount += binCounts[b]
+			rightAreas[b] = box.SurfaceArea()
+			rightCounts[b] = rightCount
+		}
+
+		box = emptyBox()
+		leftCount := 0
+		for b := 1; b < bins; b++ {
+			box.expandByBox(&binBoxes[b-1])
+			leftCount += binCounts[b-1]
+			if leftCount == 0 || rightCounts[b] == 0 {
+				continue
+			}
+			cost := bvh.splitCost(leftCount, box.SurfaceArea(), rightCounts[b], rightAreas[b], parentArea)
+			if cost < bestCost {
+				bestCost = cost
+				bestAxis = axis
+				bestSplit = b
+				bestScale = scale
+			}
+		}
+	}
+
+	if bestAxis < 0 || !bvh.shouldSplit(count, bestCost) {
+		return nil, nil, false
+	}
+
+	var leftElements, rightElements []int
+	for i := node.StartIndex; i < node.EndIndex; i++ {
+		if binIndex(bvh.centroid(i, bestAxis), centroidMin[bestAxis], bestScale, bins) < bestSplit {
+			leftElements = append(leftElements, i)
+		} else {
+			rightElements = append(rightElements, i)
+		}
+	}
+
+	return leftElements, rightElements, true
+}
+
+// splitCost returns the SAH cost of splitting a node into two children with the given triangle counts and surface areas
+func (bvh *BVH) splitCost(leftCount int, leftArea float64, rightCount int, rightArea float64, parentArea float64) float64 {
+	return bvh.options.SAHCost.Traversal +
+		bvh.options.SAHCost.Intersection*(float64(leftCount)*leftArea+float64(rightCount)*rightArea)/parentArea
+}
+
+// shouldSplit reports whether a node with count triangles should be split given the cost of its best split.
+// Nodes above maxTrianglesPerNode are always split, smaller ones only if splitting is cheaper than a leaf.
+func (bvh *BVH) shouldSplit(count int, splitCost float64) bool {
+	if count > bvh.options.MaxTrianglesPerNode {
+		return true
+	}
+	return splitCost < bvh.options.SAHCost.Intersection*float64(count)
+}
+
+// centroid returns the center of the bounding box at pos along the given axis
+func (bvh *BVH) centroid(pos, axis int) float64 {
+	return (bvh.bboxArray[pos*7+1+axis] + bvh.bboxArray[pos*7+4+axis]) * 0.5
+}
+
+// binIndex maps a centroid coordinate to one of bins equally sized bins starting at min
+func binIndex(c, min, scale float64, bins int) int {
+	b := int((c - min) * scale)
+	if b >= bins {
+		b = bins - 1
+	}
+	if b < 0 {
+		b = 0
+	}
+	return b
+}
@@ -0,0 +1,63 @@
+package bvhtree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// clusteredTriangles returns the vertex array of small triangles gathered in a few dense clusters of different sizes,
+// plus some scattered across a cube of side 100
+func clusteredTriangles(seed int64) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	var vertexArray []float64
+
+	appendTriangles := func(count int, center [3]float64, spread float64) {
+		for i := 0; i < count; i++ {
+			x := center[0] + r.NormFloat64()*spread
+			y := center[1] + r.NormFloat64()*spread
+			z := center[2] + r.NormFloat64()*spread
+			for v := 0; v < 3; v++ {
+				vertexArray = append(vertexArray, x+r.Float64()*0.5, y+r.Float64()*0.5, z+r.Float64()*0.5)
+			}
+		}
+	}
+
+	for cluster := 0; cluster < 6; cluster++ {
+		center := [3]float64{r.Float64()*90 - 45, r.Float64()*90 - 45, r.Float64()*90 - 45}
+		appendTriangles(200+r.Intn(800), center, 0.5+r.Float64()*3)
+	}
+	appendTriangles(200, [3]float64{0, 0, 0}, 30)
+
+	return vertexArray
+}
+
+func TestSAHBruteForce(t *testing.T) {
+	vertexArray := clusteredTriangles(1)
+	triangles := make([]int, len(vertexArray)/9)
+	for i := range triangles {
+		triangles[i] = i
+	}
+
+	for _, strategy := range []SplitStrategy{SplitSAH, SplitBinnedSAH} {
+		options := DefaultBuildOptions()
+		options.SplitStrategy = strategy
+		bvh := NewBVHFromVertexArrayWithOptions(vertexArray, options)
+
+		checkSameTriangles(t, "NodeTriangles", bvh.NodeTriangles(bvh.rootNode, nil), triangles)
+		checkQueriesBruteForce(t, bvh, triangles, 3)
+	}
+}
+
+func TestSAHCostBelowMidpoint(t *testing.T) {
+	vertexArray := clusteredTriangles(2)
+
+	options := DefaultBuildOptions()
+	midpointCost := NewBVHFromVertexArrayWithOptions(vertexArray, options).Cost()
+
+	for _, strategy := range []SplitStrategy{SplitSAH, SplitBinnedSAH} {
+		options.SplitStrategy = strategy
+		if cost := NewBVHFromVertexArrayWithOptions(vertexArray, options).Cost(); cost >= midpointCost {
+			t.Errorf("SAH cost %v of strategy %v, expected less than the midpoint cost %v", cost, strategy, midpointCost)
+		}
+	}
+}
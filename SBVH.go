@@ -0,0 +1,499 @@
+package bvhtree
+
+import (
+	"math"
+	"sort"
+)
+
+// maxClipVertices is the maximum number of vertices of a triangle clipped against two parallel planes
+const maxClipVertices = 5
+
+// sbvhTask is a node of a spatial split BVH under construction together with the triangle references it contains
+type sbvhTask struct {
+	node       *Node
+	references []BoundingBox
+}
+
+// referenceSplit describes the best split found for the references of a node
+type referenceSplit struct {
+	cost     float64
+	axis     int
+	spatial  bool
+	position float64 // Plane of a spatial split
+
+	// Centroid bins of an object split, references in bins below bin go to the left child
+	bin              int
+	binMin, binScale float64
+}
+
+// isLeft reports whether an object split puts ref into the left child
+func (split *referenceSplit) isLeft(ref *BoundingBox, bins int) bool {
+	return binIndex(ref.centroid(split.axis), split.binMin, split.binScale, bins) < split.bin
+}
+
+// buildSBVH builds the tree below root as a spatial split BVH (Stich et al. 2009). Besides binned SAH object splits,
+// nodes whose best object split leaves children overlapping by more than SpatialSplitOverlap of the root's surface area
+// also try splitting space along a plane. Triangles crossing that plane are clipped and referenced from both children,
+// so the bboxArray ends up with one entry per reference, each bounding the part of its triangle inside the leaf.
+func (bvh *BVH) buildSBVH(root *Node, progress *buildProgress) {
+	references := make([]BoundingBox, root.ElementCount())
+	referenceCounts := make([]int32, len(bvh.vertexArray)/9)
+	for i := range references {
+		GetBox(bvh.bboxArray, root.StartIndex+i, &references[i])
+		referenceCounts[references[i].TriangleID] = 1
+	}
+
+	minOverlap := bvh.options.SpatialSplitOverlap * root.SurfaceArea()
+
+	var leaves []sbvhTask
+	tasks := []sbvhTask{{root, references}}
+
+	for len(tasks) > 0 {
+		task := tasks[len(tasks)-1]
+		tasks = tasks[:len(tasks)-1]
+
+		left, right, ok := bvh.partitionReferences(task, minOverlap, referenceCounts)
+		if !ok {
+			leaves = append(leaves, task)
+
+			// A triangle is finished once its last reference has ended up in a leaf
+			finished := 0
+			for _, ref := range task.references {
+				referenceCounts[ref.TriangleID]--
+				if referenceCounts[ref.TriangleID] == 0 {
+					finished++
+				}
+			}
+			progress.leafCreated(finished)
+			continue
+		}
+
+		node := task.node
+		node.Node0 = NewBVHNode(&Vector3{}, &Vector3{}, 0, 0, node.Level+1)
+		node.Node1 = NewBVHNode(&Vector3{}, &Vector3{}, 0, 0, node.Level+1)
+		node.ClearShapes()
+
+		tasks = append(tasks, sbvhTask{node.Node1, right}, sbvhTask{node.Node0, left})
+	}
+
+	// Lay out the references leaf by leaf in depth-first order
+	referenceCount := 0
+	for _, leaf := range leaves {
+		referenceCount += len(leaf.references)
+	}
+
+	bboxArray := make([]float64, referenceCount*7)
+	pos := 0
+	for _, leaf := range leaves {
+		leaf.node.StartIndex = pos
+		for _, ref := range leaf.references {
+			SetBox(bboxArray, pos, ref.TriangleID, ref.MinX, ref.MinY, ref.MinZ, ref.MaxX, ref.MaxY, ref.MaxZ)
+			pos++
+		}
+		leaf.node.EndIndex = pos
+	}
+
+	bvh.bboxArray = bboxArray
+	bvh.bboxHelper = make([]float64, len(bboxArray))
+	copy(bvh.bboxHelper, bboxArray)
+	bvh.duplicateReferences = referenceCount > len(references)
+
+	bvh.refitNode(root)
+}
+
+// partitionReferences splits the references of a node into those of its two children.
+// Spatial splits are only evaluated when the children of the best object split overlap by more than minOverlap.
+func (bvh *BVH) partitionReferences(task sbvhTask, minOverlap float64, referenceCounts []int32) ([]BoundingBox, []BoundingBox, bool) {
+	references := task.references
+	count := len(references)
+	if count < 2 {
+		return nil, nil, false
+	}
+	if bvh.options.MaxDepth > 0 && task.node.Level >= bvh.options.MaxDepth {
+		return nil, nil, false
+	}
+
+	bounds := emptyBox()
+	for i := range references {
+		bounds.expandByBox(&references[i])
+	}
+	parentArea := bounds.SurfaceArea()
+	if parentArea <= 0 {
+		parentArea = 1
+	}
+
+	best, ok := bvh.objectSplit(references, parentArea)
+	if !ok || bvh.splitOverlap(references, best) > minOverlap {
+		if spatial, spatialOk := bvh.spatialSplit(references, &bounds, parentArea); spatialOk && (!ok || spatial.cost < best.cost) {
+			best, ok = spatial, true
+		}
+	}
+
+	if !ok || !bvh.shouldSplit(count, best.cost) {
+		return nil, nil, false
+	}
+
+	if best.spatial {
+		left, right, shared := bvh.splitReferencesSpatial(references, best)
+		if len(left) < count && len(right) < count {
+			for _, triIndex := range shared {
+				referenceCounts[triIndex]++
+			}
+			return left, right, true
+		}
+
+		// Every reference straddles the plane, splitting would never terminate
+		if best, ok = bvh.objectSplit(references, parentArea); !ok {
+			return nil, nil, false
+		}
+	}
+
+	bins := bvh.sahBins()
+	var left, right []BoundingBox
+	for i := range references {
+		if best.isLeft(&references[i], bins) {
+			left = append(left, references[i])
+		} else {
+			right = append(right, references[i])
+		}
+	}
+	if len(left) == 0 || len(right) == 0 {
+		return nil, nil, false
+	}
+
+	return left, right, true
+}
+
+// objectSplit finds the binned SAH split of the reference centroids with the lowest cost, like partitionBinnedSAH
+func (bvh *BVH) objectSplit(references []BoundingBox, parentArea float64) (referenceSplit, bool) {
+	bins := bvh.sahBins()
+
+	centroidMin := [3]float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	centroidMax := [3]float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for i := range references {
+		for axis := 0; axis < 3; axis++ {
+			c := references[i].centroid(axis)
+			centroidMin[axis] = math.Min(centroidMin[axis], c)
+			centroidMax[axis] = math.Max(centroidMax[axis], c)
+		}
+	}
+
+	binBoxes := make([]BoundingBox, bins)
+	binCounts := make([]int, bins)
+	rightAreas := make([]float64, bins)
+	rightCounts := make([]int, bins)
+	best := referenceSplit{cost: math.Inf(1), axis: -1}
+
+	for axis := 0; axis < 3; axis++ {
+		extent := centroidMax[axis] - centroidMin[axis]
+		if extent <= 0 {
+			continue
+		}
+		scale := float64(bins) / extent
+
+		for b := 0; b < bins; b++ {
+			binBoxes[b] = emptyBox()
+			binCounts[b] = 0
+		}
+		for i := range references {
+			b := binIndex(references[i].centroid(axis), centroidMin[axis], scale, bins)
+			binBoxes[b].expandByBox(&references[i])
+			binCounts[b]++
+		}
+
+		box := emptyBox()
+		rightCount := 0
+		for b := bins - 1; b > 0; b-- {
+			box.expandByBox(&binBoxes[b])
+			rightCount += binCounts[b]
+			rightAreas[b] = box.SurfaceArea()
+			rightCounts[b] = rightCount
+		}
+
+		box = emptyBox()
+		leftCount := 0
+		for b := 1; b < bins; b++ {
+			box.expandByBox(&binBoxes[b-1])
+			leftCount += binCounts[b-1]
+			if leftCount == 0 || rightCounts[b] == 0 {
+				continue
+			}
+			cost := bvh.splitCost(leftCount, box.SurfaceArea(), rightCounts[b], rightAreas[b], parentArea)
+			if cost < best.cost {
+				best = referenceSplit{cost: cost, axis: axis, bin: b, binMin: centroidMin[axis], binScale: scale}
+			}
+		}
+	}
+
+	return best, best.axis >= 0
+}
+
+// splitOverlap returns the surface area of the overlap between the two children of an object split
+func (bvh *BVH) splitOverlap(references []BoundingBox, split referenceSplit) float64 {
+	bins := bvh.sahBins()
+	left := emptyBox()
+	right := emptyBox()
+	for i := range references {
+		if split.isLeft(&references[i], bins) {
+			left.expandByBox(&references[i])
+		} else {
+			right.expandByBox(&references[i])
+		}
+	}
+
+	return CalcSurfaceArea(
+		math.Min(left.MaxX, right.MaxX)-math.Max(left.MinX, right.MinX),
+		math.Min(left.MaxY, right.MaxY)-math.Max(left.MinY, right.MinY),
+		math.Min(left.MaxZ, right.MaxZ)-math.Max(left.MinZ, right.MinZ),
+	)
+}
+
+// spatialSplit finds the split plane with the lowest SAH cost among the boundaries of equally sized bins spanning bounds.
+// References covering several bins are clipped to each of them, they enter the leftmost and exit the rightmost bin they cover.
+func (bvh *BVH) spatialSplit(references []BoundingBox, bounds *BoundingBox, parentArea float64) (referenceSplit, bool) {
+	bins := bvh.sahBins()
+	count := len(references)
+
+	binBoxes := make([]BoundingBox, bins)
+	entries := make([]int, bins)
+	exits := make([]int, bins)
+	rightAreas := make([]float64, bins)
+	rightCounts := make([]int, bins)
+	best := referenceSplit{cost: math.Inf(1), axis: -1, spatial: true}
+
+	for axis := 0; axis < 3; axis++ {
+		boundsMin := bounds.axisMin(axis)
+		extent := bounds.axisMax(axis) - boundsMin
+		if extent <= 0 {
+			continue
+		}
+		binSize := extent / float64(bins)
+		scale := 1 / binSize
+
+		for b := 0; b < bins; b++ {
+			binBoxes[b] = emptyBox()
+			entries[b] = 0
+			exits[b] = 0
+		}
+
+		for i := range references {
+			ref := &references[i]
+			first := binIndex(ref.axisMin(axis), boundsMin, scale, bins)
+			last := binIndex(ref.axisMax(axis), boundsMin, scale, bins)
+			entries[first]++
+			exits[last]++
+
+			if first == last {
+				binBoxes[first].expandByBox(ref)
+				continue
+			}
+			for b := first; b <= last; b++ {
+				lo := math.Max(boundsMin+float64(b)*binSize, ref.axisMin(axis))
+				hi := math.Min(boundsMin+float64(b+1)*binSize, ref.axisMax(axis))
+				if clipped, ok := bvh.clipReference(ref, axis, lo, hi); ok {
+					binBoxes[b].expandByBox(&clipped)
+				}
+			}
+		}
+
+		box := emptyBox()
+		rightCount := 0
+		for b := bins - 1; b > 0; b-- {
+			box.expandByBox(&binBoxes[b])
+			rightCount += exits[b]
+			rightAreas[b] = box.SurfaceArea()
+			rightCounts[b] = rightCount
+		}
+
+		box = emptyBox()
+		leftCount := 0
+		for b := 1; b < bins; b++ {
+			box.expandByBox(&binBoxes[b-1])
+			leftCount += entries[b-1]
+			if leftCount == 0 || rightCounts[b] == 0 || leftCount == count || rightCounts[b] == count {
+				continue
+			}
+			cost := bvh.splitCost(leftCount, box.SurfaceArea(), rightCounts[b], rightAreas[b], parentArea)
+			if cost < best.cost {
+				best = referenceSplit{cost: cost, axis: axis, position: boundsMin + float64(b)*binSize, spatial: true}
+			}
+		}
+	}
+
+	return best, best.axis >= 0
+}
+
+// splitReferencesSpatial distributes the references on both sides of a spatial split plane.
+// References crossing the plane are clipped to each side, their triangles are returned as shared.
+func (bvh *BVH) splitReferencesSpatial(references []BoundingBox, split referenceSplit) ([]BoundingBox, []BoundingBox, []int) {
+	var left, right []BoundingBox
+	var shared []int
+
+	for i := range references {
+		ref := &references[i]
+		if ref.axisMax(split.axis) <= split.position {
+			left = append(left, *ref)
+			continue
+		}
+		if ref.axisMin(split.axis) >= split.position {
+			right = append(right, *ref)
+			continue
+		}
+
+		leftPart, leftOk := bvh.clipReference(ref, split.axis, ref.axisMin(split.axis), split.position)
+		rightPart, rightOk := bvh.clipReference(ref, split.axis, split.position, ref.axisMax(split.axis))
+		switch {
+		case leftOk && rightOk:
+			left = append(left, leftPart)
+			right = append(right, rightPart)
+			shared = append(shared, ref.TriangleID)
+		case leftOk:
+			left = append(left, leftPart)
+		case rightOk:
+			right = append(right, rightPart)
+		default:
+			// Clipping lost the triangle to rounding, keep it whole
+			left = append(left, *ref)
+		}
+	}
+
+	return left, right, shared
+}
+
+// clipReference returns the bounding box of the part of the referenced triangle between lo and hi along axis,
+// limited to the box of the reference. It fails if nothing of the triangle is left.
+func (bvh *BVH) clipReference(ref *BoundingBox, axis int, lo, hi float64) (BoundingBox, bool) {
+	var polygon, clipped [maxClipVertices][3]float64
+	triIndex := ref.TriangleID
+	for v := 0; v < 3; v++ {
+		polygon[v] = [3]float64{
+			bvh.vertexArray[triIndex*9+v*3],
+			bvh.vertexArray[triIndex*9+v*3+1],
+			bvh.vertexArray[triIndex*9+v*3+2],
+		}
+	}
+
+	n := clipPolygon(&polygon, 3, &clipped, axis, lo, 1)
+	n = clipPolygon(&clipped, n, &polygon, axis, hi, -1)
+	if n == 0 {
+		return BoundingBox{}, false
+	}
+
+	boxMin, boxMax := polygon[0], polygon[0]
+	for _, p := range polygon[1:n] {
+		for k := 0; k < 3; k++ {
+			if p[k] < boxMin[k] {
+				boxMin[k] = p[k]
+			}
+			if p[k] > boxMax[k] {
+				boxMax[k] = p[k]
+			}
+		}
+	}
+
+	// Rounding in the clipping may leave the polygon slightly outside of the reference and the slab
+	limitMin := [3]float64{ref.MinX, ref.MinY, ref.MinZ}
+	limitMax := [3]float64{ref.MaxX, ref.MaxY, ref.MaxZ}
+	if lo > limitMin[axis] {
+		limitMin[axis] = lo
+	}
+	if hi < limitMax[axis] {
+		limitMax[axis] = hi
+	}
+	for k := 0; k < 3; k++ {
+		if limitMin[k] > boxMin[k] {
+			boxMin[k] = limitMin[k]
+		}
+		if limitMax[k] < boxMax[k] {
+			boxMax[k] = limitMax[k]
+		}
+		if boxMin[k] > boxMax[k] {
+			return BoundingBox{}, false
+		}
+	}
+
+	return BoundingBox{
+		TriangleID: triIndex,
+		MinX:       boxMin[0],
+		MinY:       boxMin[1],
+		MinZ:       boxMin[2],
+		MaxX:       boxMax[0],
+		MaxY:       boxMax[1],
+		MaxZ:       boxMax[2],
+	}, true
+}
+
+// clipPolygon clips the polygon with n vertices against the plane at position along axis, keeping the side where
+// sign * (p[axis] - position) >= 0, and stores the result in clipped. It returns the number of vertices left.
+func clipPolygon(polygon *[maxClipVertices][3]float64, n int, clipped *[maxClipVertices][3]float64, axis int, position, sign float64) int {
+	m := 0
+	for i := 0; i < n; i++ {
+		current := polygon[i]
+		next := polygon[(i+1)%n]
+		dc := sign * (current[axis] - position)
+		dn := sign * (next[axis] - position)
+
+		if dc >= 0 {
+			clipped[m] = current
+			m++
+		}
+		if (dc >= 0) != (dn >= 0) {
+			t := dc / (dc - dn)
+			for k := 0; k < 3; k++ {
+				clipped[m][k] = current[k] + (next[k]-current[k])*t
+			}
+			clipped[m][axis] = position
+			m++
+		}
+	}
+	return m
+}
+
+// sahBins returns the number of bins used by the binned SAH strategies
+func (bvh *BVH) sahBins() int {
+	if bvh.options.SAHCost.Bins < 2 {
+		return 2
+	}
+	return bvh.options.SAHCost.Bins
+}
+
+// dedupeTriangles sorts triangle indices and removes repeated ones in place
+func dedupeTriangles(triangles []int) []int {
+	if len(triangles) < 2 {
+		return triangles
+	}
+
+	sort.Ints(triangles)
+	n := 1
+	for _, triIndex := range triangles[1:] {
+		if triIndex != triangles[n-1] {
+			triangles[n] = triIndex
+			n++
+		}
+	}
+	return triangles[:n]
+}
+
+// triangleSet records the triangles already reported by a query on a BVH whose leaves share triangles.
+// A nil set is used for all other trees and accepts every triangle.
+type triangleSet map[int]struct{}
+
+// newTriangleSet returns the set used to report every triangle once, nil if the BVH references each triangle only once
+func (bvh *BVH) newTriangleSet() triangleSet {
+	if !bvh.duplicateReferences {
+		return nil
+	}
+	return triangleSet{}
+}
+
+// add records triIndex and reports whether it was not in the set yet
+func (set triangleSet) add(triIndex int) bool {
+	if set == nil {
+		return true
+	}
+	if _, ok := set[triIndex]; ok {
+		return false
+	}
+	set[triIndex] = struct{}{}
+	return true
+}
@@ -0,0 +1,216 @@
+package bvhtree
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// sliverTriangles returns the vertex array of count long, thin triangles crossing a cube of side 100 in random directions
+func sliverTriangles(count int, seed int64) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	vertexArray := make([]float64, 0, count*9)
+
+	for i := 0; i < count; i++ {
+		x, y, z := r.Float64()*100-50, r.Float64()*100-50, r.Float64()*100-50
+		dx, dy, dz := r.Float64()*80-40, r.Float64()*80-40, r.Float64()*80-40
+		vertexArray = append(vertexArray,
+			x-dx, y-dy, z-dz,
+			x+dx, y+dy, z+dz,
+			x+r.Float64()-0.5, y+r.Float64()-0.5, z+r.Float64()-0.5)
+	}
+
+	return vertexArray
+}
+
+// newSBVH builds a BVH with the spatial split strategy and otherwise default options
+func newSBVH(vertexArray []float64) *BVH {
+	options := DefaultBuildOptions()
+	options.SplitStrategy = SplitSpatialSAH
+	return NewBVHFromVertexArrayWithOptions(vertexArray, options)
+}
+
+// checkUniqueTriangles fails the test if a triangle index is listed more than once and returns the indices sorted
+func checkUniqueTriangles(t *testing.T, query string, triangles []int) []int {
+	t.Helper()
+
+	sorted := append([]int(nil), triangles...)
+	sort.Ints(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			t.Fatalf("%s reported triangle %d more than once", query, sorted[i])
+		}
+	}
+	return sorted
+}
+
+// checkSameTriangles fails the test unless got lists every triangle of expected exactly once and nothing else
+func checkSameTriangles(t *testing.T, query string, got, expected []int) {
+	t.Helper()
+
+	got = checkUniqueTriangles(t, query, got)
+	if len(got) != len(expected) || (len(got) > 0 && !reflect.DeepEqual(got, expected)) {
+		t.Fatalf("%s reported %d triangles, expected %d", query, len(got), len(expected))
+	}
+}
+
+func TestSBVHDuplicateReferences(t *testing.T) {
+	vertexArray := sliverTriangles(1000, 1)
+	bvh := newSBVH(vertexArray)
+	if !bvh.duplicateReferences {
+		t.Fatal("spatial splits did not duplicate any triangle references")
+	}
+
+	triangleCount := len(vertexArray) / 9
+	all := make([]int, triangleCount)
+	for i := range all {
+		all[i] = i
+	}
+	checkSameTriangles(t, "NodeTriangles", bvh.NodeTriangles(bvh.rootNode, nil), all)
+
+	for _, ray := range randomRays(100, 2) {
+		var expected []int
+		closest := math.Inf(1)
+		for i := 0; i < triangleCount; i++ {
+			tri := bvh.Triangle(i)
+			if hit, ok := intersectRayTriangleHit(tri[0], tri[1], tri[2], ray.Origin, ray.Direction, ray.TMin, ray.TMax, false); ok {
+				expected = append(expected, i)
+				closest = math.Min(closest, hit.t)
+			}
+		}
+
+		var hits []int
+		for _, result := range bvh.IntersectRay(ray.Origin, ray.Direction, false) {
+			hits = append(hits, result.TriangleIndex)
+		}
+		checkSameTriangles(t, "IntersectRay", hits, expected)
+
+		result := bvh.IntersectRayClosest(ray.Origin, ray.Direction, false)
+		if (result == nil) != (len(expected) == 0) || (result != nil && result.Distance != closest) {
+			t.Fatalf("IntersectRayClosest = %+v, expected distance %v", result, closest)
+		}
+	}
+
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 100; i++ {
+		center := NewPoint(r.Float64()*100-50, r.Float64()*100-50, r.Float64()*100-50)
+		halfSize := r.Float64() * 10
+		boxMin := NewPoint(center.X-halfSize, center.Y-halfSize, center.Z-halfSize)
+		boxMax := NewPoint(center.X+halfSize, center.Y+halfSize, center.Z+halfSize)
+
+		var expected []int
+		for j := 0; j < triangleCount; j++ {
+			tri := bvh.Triangle(j)
+			if TriangleIntersectsBox(tri[0], tri[1], tri[2], boxMin, boxMax) {
+				expected = append(expected, j)
+			}
+		}
+		checkSameTriangles(t, "QueryAABB", bvh.QueryAABB(boxMin, boxMax, false), expected)
+		checkUniqueTriangles(t, "QueryAABB with boundsOnly", bvh.QueryAABB(boxMin, boxMax, true))
+
+		expected = expected[:0]
+		var point Vector3
+		for j := 0; j < triangleCount; j++ {
+			tri := bvh.Triangle(j)
+			closestPointOnTriangle(center, tri[0], tri[1], tri[2], &point)
+			if point.SubVectors(&point, center).LengthSq() <= halfSize*halfSize {
+				expected = append(expected, j)
+			}
+		}
+		var contacts []int
+		for _, contact := range bvh.QuerySphere(center, halfSize) {
+			contacts = append(contacts, contact.TriangleIndex)
+		}
+		checkSameTriangles(t, "QuerySphere", contacts, expected)
+	}
+}
+
+func TestSBVHFrustum(t *testing.T) {
+	vertexArray := sliverTriangles(1000, 1)
+	bvh := newSBVH(vertexArray)
+
+	// A box shaped frustum covering a quarter of the scene
+	planes := []Plane{
+		NewPlane(NewPoint(1, 0, 0), NewPoint(-10, 0, 0)),
+		NewPlane(NewPoint(-1, 0, 0), NewPoint(40, 0, 0)),
+		NewPlane(NewPoint(0, 1, 0), NewPoint(0, -30, 0)),
+		NewPlane(NewPoint(0, -1, 0), NewPoint(0, 20, 0)),
+		NewPlane(NewPoint(0, 0, 1), NewPoint(0, 0, -50)),
+		NewPlane(NewPoint(0, 0, -1), NewPoint(0, 0, 50)),
+	}
+
+	result := bvh.QueryFrustum(planes)
+	reported := map[int]bool{}
+	for _, triIndex := range checkUniqueTriangles(t, "QueryFrustum", result.Triangles) {
+		reported[triIndex] = true
+	}
+	for _, node := range result.Nodes {
+		for _, triIndex := range checkUniqueTriangles(t, "NodeTriangles", bvh.NodeTriangles(node, nil)) {
+			reported[triIndex] = true
+		}
+	}
+
+	for i := 0; i < len(vertexArray)/9; i++ {
+		tri := bvh.Triangle(i)
+		vertexInside, outsidePlane := false, false
+		for _, vertex := range tri {
+			inside := true
+			for _, plane := range planes {
+				inside = inside && plane.DistanceToPoint(vertex) > 0
+			}
+			vertexInside = vertexInside || inside
+		}
+		for _, plane := range planes {
+			outside := true
+			for _, vertex := range tri {
+				outside = outside && plane.DistanceToPoint(vertex) < 0
+			}
+			outsidePlane = outsidePlane || outside
+		}
+
+		if vertexInside && !reported[i] {
+			t.Fatalf("QueryFrustum missed triangle %d with a vertex inside the frustum", i)
+		}
+		if outsidePlane && reported[i] {
+			t.Fatalf("QueryFrustum reported triangle %d lying outside a plane of the frustum", i)
+		}
+	}
+}
+
+func TestSBVHTrianglePairs(t *testing.T) {
+	vertexArray := sliverTriangles(1000, 1)
+	otherVertexArray := sliverTriangles(300, 4)
+	bvh := newSBVH(vertexArray)
+	other := newSBVH(otherVertexArray)
+
+	var expectedSelf, expectedOther []TrianglePair
+	for i := 0; i < len(vertexArray)/9; i++ {
+		a := bvh.Triangle(i)
+		for j := i + 1; j < len(vertexArray)/9; j++ {
+			b := bvh.Triangle(j)
+			if TrianglesIntersect(a[0], a[1], a[2], b[0], b[1], b[2]) {
+				expectedSelf = append(expectedSelf, TrianglePair{i, j})
+			}
+		}
+		for j := 0; j < len(otherVertexArray)/9; j++ {
+			b := other.Triangle(j)
+			if TrianglesIntersect(a[0], a[1], a[2], b[0], b[1], b[2]) {
+				expectedOther = append(expectedOther, TrianglePair{i, j})
+			}
+		}
+	}
+
+	pairs := bvh.SelfIntersections()
+	sortPairs(pairs)
+	if len(expectedSelf) == 0 || !reflect.DeepEqual(pairs, expectedSelf) {
+		t.Errorf("SelfIntersections() found %d pairs, expected %d", len(pairs), len(expectedSelf))
+	}
+
+	pairs = bvh.IntersectBVH(other, nil)
+	sortPairs(pairs)
+	if len(expectedOther) == 0 || !reflect.DeepEqual(pairs, expectedOther) {
+		t.Errorf("IntersectBVH() found %d pairs, expected %d", len(pairs), len(expectedOther))
+	}
+}
@@ -0,0 +1,232 @@
+package bvhtree
+
+// SelfIntersections returns all pairs of intersecting triangles within the mesh, with Triangle0 < Triangle1.
+// Neighbouring triangles sharing an edge are never reported. Triangles sharing a single vertex are only reported
+// if they intersect somewhere else than in that vertex.
+func (bvh *BVH) SelfIntersections() []TrianglePair {
+	var pairs []TrianglePair
+	bvh.SelfIntersectionsFunc(func(pair TrianglePair) bool {
+		pairs = append(pairs, pair)
+		return true
+	})
+	return pairs
+}
+
+// SelfIntersectionsFunc calls callback for every pair of intersecting triangles within the mesh, see SelfIntersections.
+// The tree is traversed against itself, so every pair of nodes is visited at most once.
+// The query stops early when callback returns false.
+func (bvh *BVH) SelfIntersectionsFunc(callback func(pair TrianglePair) bool) {
+	if bvh.duplicateReferences {
+		callback = uniquePairs(callback)
+	}
+
+	nodes := bvh.flatTree()
+	nodesToVisit := []flatNodePair{{0, 0}}
+
+	for len(nodesToVisit) > 0 {
+		pair := nodesToVisit[len(nodesToVisit)-1]
+		nodesToVisit = nodesToVisit[:len(nodesToVisit)-1]
+
+		node0, node1 := &nodes[pair.index0], &nodes[pair.index1]
+		isLeaf0 := node0.isLeaf()
+		isLeaf1 := node1.isLeaf()
+
+		if pair.index0 == pair.index1 {
+			if isLeaf0 {
+				if !bvh.selfIntersectLeaves(node0, node0, callback) {
+					return
+				}
+				continue
+			}
+			index0, index1 := node0.children()
+			nodesToVisit = append(nodesToVisit,
+				flatNodePair{index0, index1},
+				flatNodePair{index1, index1},
+				flatNodePair{index0, index0})
+			continue
+		}
+
+		if !node0.overlaps(node1) {
+			continue
+		}
+
+		if isLeaf0 && isLeaf1 {
+			if !bvh.selfIntersectLeaves(node0, node1, callback) {
+				return
+			}
+			continue
+		}
+
+		if isLeaf1 || (!isLeaf0 && node0.surfaceArea() >= node1.surfaceArea()) {
+			index0, index1 := node0.children()
+			nodesToVisit = append(nodesToVisit, flatNodePair{index1, pair.index1}, flatNodePair{index0, pair.index1})
+		} else {
+			index0, index1 := node1.children()
+			nodesToVisit = append(nodesToVisit, flatNodePair{pair.index0, index1}, flatNodePair{pair.index0, index0})
+		}
+	}
+}
+
+// selfIntersectLeaves tests the triangles of two leaf nodes against each other and reports whether the query should continue.
+// If both leaves are the same node, every pair of its triangles is tested once.
+func (bvh *BVH) selfIntersectLeaves(leaf0, leaf1 *flatNode, callback func(pair TrianglePair) bool) bool {
+	var a, b [3]Vector3
+	var boxMin, boxMax Vector3
+
+	for j := int(leaf1.offset); j < int(leaf1.offset+leaf1.count); j++ {
+		boxMin.SetFromArray(bvh.bboxArray, j*7+1)
+		boxMax.SetFromArray(bvh.bboxArray, j*7+4)
+		triIndex1 := int(bvh.bboxArray[j*7])
+		b[0].SetFromArray(bvh.vertexArray, triIndex1*9)
+		b[1].SetFromArray(bvh.vertexArray, triIndex1*9+3)
+		b[2].SetFromArray(bvh.vertexArray, triIndex1*9+6)
+
+		end := int(leaf0.offset + leaf0.count)
+		if leaf0 == leaf1 {
+			end = j
+		}
+
+		for i := int(leaf0.offset); i < end; i++ {
+			if !boxOverlapsArray(bvh.bboxArray, i, &boxMin, &boxMax) {
+				continue
+			}
+
+			triIndex0 := int(bvh.bboxArray[i*7])
+			if triIndex0 == triIndex1 {
+				continue
+			}
+			a[0].SetFromArray(bvh.vertexArray, triIndex0*9)
+			a[1].SetFromArray(bvh.vertexArray, triIndex0*9+3)
+			a[2].SetFromArray(bvh.vertexArray, triIndex0*9+6)
+
+			if !neighbourTrianglesIntersect(&a, &b) {
+				continue
+			}
+
+			pair := TrianglePair{triIndex0, triIndex1}
+			if triIndex0 > triIndex1 {
+				pair = TrianglePair{triIndex1, triIndex0}
+			}
+			if !callback(pair) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// neighbourTrianglesIntersect checks if two triangles of the same mesh intersect, ignoring the vertices they share.
+// Triangles sharing an edge are considered not to intersect. Coplanar triangles sharing a vertex intersect
+// if they overlap anywhere else, including along an edge.
+func neighbourTrianglesIntersect(a, b *[3]Vector3) bool {
+	sharedA, sharedB, shared := -1, -1, 0
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if a[i] == b[j] {
+				sharedA, sharedB = i, j
+				shared++
+			}
+		}
+	}
+
+	switch shared {
+	case 0:
+		return TrianglesIntersect(&a[0], &a[1], &a[2], &b[0], &b[1], &b[2])
+	case 1:
+		if normal, ok := sharedPlaneNormal(a, b); ok {
+			return coplanarNeighboursIntersect(normal, a, sharedA, b, sharedB)
+		}
+		return crossingNeighboursIntersect(a, sharedA, b, sharedB)
+	default:
+		return false
+	}
+}
+
+// crossingNeighboursIntersect checks if two triangles in different planes sharing the vertices a[sharedA] and b[sharedB]
+// intersect somewhere else than in that vertex. Each triangle meets the plane of the other one in a segment starting at
+// the shared vertex, or only in that vertex. Both segments lie on the line where the planes meet, so the triangles
+// intersect exactly if the segments leave the shared vertex in the same direction.
+func crossingNeighboursIntersect(a *[3]Vector3, sharedA int, b *[3]Vector3, sharedB int) bool {
+	var edge1, edge2, normalA, normalB, directionA, directionB Vector3
+	normalA.CrossVectors(edge1.SubVectors(&a[1], &a[0]), edge2.SubVectors(&a[2], &a[0]))
+	normalB.CrossVectors(edge1.SubVectors(&b[1], &b[0]), edge2.SubVectors(&b[2], &b[0]))
+
+	if !planeSegmentDirection(a, sharedA, &normalB, &directionA) || !planeSegmentDirection(b, sharedB, &normalA, &directionB) {
+		return false
+	}
+	return directionA.Dot(&directionB) > 0
+}
+
+// planeSegmentDirection sets direction to point from the vertex tri[shared] to the far end of the segment in which the
+// triangle meets the plane through that vertex with the given normal. It reports false if the triangle only touches
+// the plane in that vertex, or lies in it.
+func planeSegmentDirection(tri *[3]Vector3, shared int, normal, direction Point) bool {
+	var toP, toQ Vector3
+	toP.SubVectors(&tri[(shared+1)%3], &tri[shared])
+	toQ.SubVectors(&tri[(shared+2)%3], &tri[shared])
+
+	dp, dq := normal.Dot(&toP), normal.Dot(&toQ)
+	if (dp > 0 && dq > 0) || (dp < 0 && dq < 0) || dp == dq {
+		return false
+	}
+
+	// The segment ends where the opposite edge crosses the plane
+	s := dp / (dp - dq)
+	direction.Copy(toP.MultiplyScalar(1 - s)).Add(toQ.MultiplyScalar(s))
+	return true
+}
+
+// sharedPlaneNormal returns the normal of the larger of two triangles if the other one lies in its plane.
+// Using the larger triangle keeps a degenerate triangle, which has no normal, from passing as coplanar.
+func sharedPlaneNormal(a, b *[3]Vector3) (*Vector3, bool) {
+	var edge1, edge2, normalA, normalB, diff Vector3
+	normalA.CrossVectors(edge1.SubVectors(&a[1], &a[0]), edge2.SubVectors(&a[2], &a[0]))
+	normalB.CrossVectors(edge1.SubVectors(&b[1], &b[0]), edge2.SubVectors(&b[2], &b[0]))
+
+	normal, other, origin := &normalA, b, &a[0]
+	if normalB.LengthSq() > normalA.LengthSq() {
+		normal, other, origin = &normalB, a, &b[0]
+	}
+
+	for i := range other {
+		if normal.Dot(diff.SubVectors(&other[i], origin)) != 0 {
+			return nil, false
+		}
+	}
+	return normal, true
+}
+
+// coplanarNeighboursIntersect checks if two triangles lying in the same plane with the given normal and sharing
+// the vertices a[sharedA] and b[sharedB] intersect somewhere else than in that vertex. Near the shared vertex each
+// triangle covers the wedge between its two edges leaving it, and both triangles are convex, so they overlap exactly
+// if one of the edges lies inside the wedge of the other triangle.
+func coplanarNeighboursIntersect(normal Point, a *[3]Vector3, sharedA int, b *[3]Vector3, sharedB int) bool {
+	project := coplanarProjection(normal)
+	v := project(&a[sharedA])
+	a1, a2 := project(&a[(sharedA+1)%3]), project(&a[(sharedA+2)%3])
+	b1, b2 := project(&b[(sharedB+1)%3]), project(&b[(sharedB+2)%3])
+
+	return wedgeContains2D(v, b1, b2, a1) || wedgeContains2D(v, b1, b2, a2) ||
+		wedgeContains2D(v, a1, a2, b1) || wedgeContains2D(v, a1, a2, b2)
+}
+
+// wedgeContains2D checks if the ray from v through p lies inside the wedge between the rays from v through e1 and e2,
+// including its boundary rays. A degenerate wedge or ray contains nothing.
+func wedgeContains2D(v, e1, e2, p [2]float64) bool {
+	if p == v {
+		return false
+	}
+
+	wedge := orient2D(v, e1, e2)
+	d1 := orient2D(v, e1, p)
+	d2 := orient2D(v, p, e2)
+
+	if wedge > 0 {
+		return d1 >= 0 && d2 >= 0
+	}
+	if wedge < 0 {
+		return d1 <= 0 && d2 <= 0
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package bvhtree
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// sortPairs sorts triangle pairs by their first and then their second triangle
+func sortPairs(pairs []TrianglePair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Triangle0 != pairs[j].Triangle0 {
+			return pairs[i].Triangle0 < pairs[j].Triangle0
+		}
+		return pairs[i].Triangle1 < pairs[j].Triangle1
+	})
+}
+
+func TestSelfIntersectionsNeighbours(t *testing.T) {
+	tests := []struct {
+		name      string
+		triangles [2][9]float64
+		intersect bool
+	}{
+		{"coplanar sharing a vertex, overlapping", [2][9]float64{
+			{0, 0, 0, 2, 0, 0, 0, 2, 0},
+			{0, 0, 0, 2, 1, 0, 1, 2, 0},
+		}, true},
+		{"coplanar sharing a vertex, one inside the other", [2][9]float64{
+			{0, 0, 0, 4, 0, 0, 0, 4, 0},
+			{0, 0, 0, 1, 0.5, 0, 0.5, 1, 0},
+		}, true},
+		{"coplanar sharing a vertex, touching along an edge", [2][9]float64{
+			{0, 0, 0, 2, 0, 0, 0, 2, 0},
+			{0, 0, 0, 1, 0, 0, 1, -1, 0},
+		}, true},
+		{"coplanar sharing a vertex, apart", [2][9]float64{
+			{0, 0, 0, 2, 0, 0, 1, 1, 0},
+			{0, 0, 0, -1, 1, 0, -2, 0, 0},
+		}, false},
+		{"coplanar sharing a vertex, opposite", [2][9]float64{
+			{0, 0, 0, 2, 0, 0, 0, 2, 0},
+			{0, 0, 0, -2, 0, 0, 0, -2, 0},
+		}, false},
+		{"sharing a vertex, crossing", [2][9]float64{
+			{0, 0, 0, 2, 0, 0, 0, 2, 0},
+			{0, 0, 0, 0.5, 0.5, 1, 0.5, 0.5, -1},
+		}, true},
+		{"sharing a vertex, apart", [2][9]float64{
+			{0, 0, 0, 2, 0, 0, 0, 2, 0},
+			{0, 0, 0, -1, 0, 1, 0, -1, 1},
+		}, false},
+		{"sharing a vertex, edge lying in the other plane", [2][9]float64{
+			{0, 0, 0, 1, 0, 0, 1, 1, 0},
+			{0, 0, 0, -1, 1, 0, 0, 1, 1},
+		}, false},
+		{"sharing an edge, coplanar and overlapping", [2][9]float64{
+			{0, 0, 0, 2, 0, 0, 0, 2, 0},
+			{0, 0, 0, 2, 0, 0, 1, 1, 0},
+		}, false},
+	}
+
+	for _, test := range tests {
+		vertexArray := append(test.triangles[0][:], test.triangles[1][:]...)
+		pairs := NewBVHFromVertexArray(vertexArray, 1).SelfIntersections()
+
+		var expected []TrianglePair
+		if test.intersect {
+			expected = []TrianglePair{{0, 1}}
+		}
+		if !reflect.DeepEqual(pairs, expected) {
+			t.Errorf("%s: SelfIntersections() = %v, expected %v", test.name, pairs, expected)
+		}
+	}
+}
+
+func TestSelfIntersectionsClosedMesh(t *testing.T) {
+	bvh := newSAHBVH(sphereMesh(32, 64, 10))
+	if pairs := bvh.SelfIntersections(); len(pairs) != 0 {
+		t.Errorf("SelfIntersections() of a sphere = %v, expected none", pairs)
+	}
+}
+
+func TestSelfIntersectionsBruteForce(t *testing.T) {
+	vertexArray := randomTriangles(400, 10, 1)
+	bvh := newSAHBVH(vertexArray)
+
+	var expected []TrianglePair
+	triangleCount := len(vertexArray) / 9
+	for i := 0; i < triangleCount; i++ {
+		a := bvh.Triangle(i)
+		for j := i + 1; j < triangleCount; j++ {
+			b := bvh.Triangle(j)
+			if TrianglesIntersect(a[0], a[1], a[2], b[0], b[1], b[2]) {
+				expected = append(expected, TrianglePair{i, j})
+			}
+		}
+	}
+
+	pairs := bvh.SelfIntersections()
+	sortPairs(pairs)
+	if len(expected) == 0 || !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("SelfIntersections() found %d pairs, expected %d", len(pairs), len(expected))
+	}
+}
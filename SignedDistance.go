@@ -0,0 +1,111 @@
+package bvhtree
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SignedDistance returns the distance between p and the closest point on the mesh, which has to be closed.
+// The distance is negative if p lies inside the mesh, see Contains, and +Inf for an empty mesh.
+func (bvh *BVH) SignedDistance(p Point) float64 {
+	closest := bvh.ClosestPoint(p, math.Inf(1))
+	if closest == nil {
+		return math.Inf(1)
+	}
+
+	distance := math.Sqrt(closest.DistanceSq)
+	if distance > 0 && bvh.Contains(p) {
+		return -distance
+	}
+	return distance
+}
+
+// SDFGrid is a regular 3D grid of signed distances.
+// The sample (x, y, z) lies at Origin + (x, y, z) * Spacing and is stored at Values[x + y*SizeX + z*SizeX*SizeY].
+type SDFGrid struct {
+	Origin              Vector3
+	Spacing             Vector3
+	SizeX, SizeY, SizeZ int
+	Values              []float64
+}
+
+// Index returns the position of the sample (x, y, z) in Values
+func (grid *SDFGrid) Index(x, y, z int) int {
+	return x + (y+z*grid.SizeY)*grid.SizeX
+}
+
+// Position stores the position of the sample (x, y, z) in result and returns result
+func (grid *SDFGrid) Position(x, y, z int, result Point) Point {
+	return result.Set(
+		grid.Origin.X+float64(x)*grid.Spacing.X,
+		grid.Origin.Y+float64(y)*grid.Spacing.Y,
+		grid.Origin.Z+float64(z)*grid.Spacing.Z,
+	)
+}
+
+// Value returns the signed distance stored for the sample (x, y, z)
+func (grid *SDFGrid) Value(x, y, z int) float64 {
+	return grid.Values[grid.Index(x, y, z)]
+}
+
+// SignedDistanceGrid samples SignedDistance on a grid spanning the extents of the root node, with resolution samples
+// along each axis including both ends. The rows of the grid are distributed over workers goroutines,
+// values below 1 use runtime.GOMAXPROCS.
+func (bvh *BVH) SignedDistanceGrid(resolution, workers int) *SDFGrid {
+	if resolution < 2 {
+		resolution = 2
+	}
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	extentsMin := bvh.rootNode.ExtentsMin
+	extentsMax := bvh.rootNode.ExtentsMax
+	steps := float64(resolution - 1)
+
+	grid := &SDFGrid{
+		Origin: *extentsMin,
+		Spacing: Vector3{
+			X: (extentsMax.X - extentsMin.X) / steps,
+			Y: (extentsMax.Y - extentsMin.Y) / steps,
+			Z: (extentsMax.Z - extentsMin.Z) / steps,
+		},
+		SizeX:  resolution,
+		SizeY:  resolution,
+		SizeZ:  resolution,
+		Values: make([]float64, resolution*resolution*resolution),
+	}
+
+	rows := grid.SizeY * grid.SizeZ
+	if workers > rows {
+		workers = rows
+	}
+
+	var nextRow int64
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var p Vector3
+
+			for {
+				row := int(atomic.AddInt64(&nextRow, 1)) - 1
+				if row >= rows {
+					return
+				}
+
+				y, z := row%grid.SizeY, row/grid.SizeY
+				for x := 0; x < grid.SizeX; x++ {
+					grid.Values[grid.Index(x, y, z)] = bvh.SignedDistance(grid.Position(x, y, z, &p))
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return grid
+}
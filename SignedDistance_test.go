@@ -0,0 +1,73 @@
+package bvhtree
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSignedDistanceSphere(t *testing.T) {
+	const radius = 10
+	bvh := newSAHBVH(sphereMesh(32, 64, radius))
+
+	// The flat faces of the mesh lie slightly inside the analytic sphere
+	const tolerance = 0.15
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		p := NewPoint(r.Float64()*40-20, r.Float64()*40-20, r.Float64()*40-20)
+		expected := p.Length() - radius
+		distance := bvh.SignedDistance(p)
+
+		if math.Abs(distance-expected) > tolerance {
+			t.Fatalf("SignedDistance(%v) = %v, expected about %v", *p, distance, expected)
+		}
+		if math.Abs(expected) > tolerance && (distance < 0) != (expected < 0) {
+			t.Fatalf("SignedDistance(%v) = %v has the wrong sign, expected about %v", *p, distance, expected)
+		}
+	}
+}
+
+func TestSignedDistanceGrid(t *testing.T) {
+	const resolution = 9
+	bvh := newSAHBVH(sphereMesh(16, 32, 10))
+	grid := bvh.SignedDistanceGrid(resolution, 1)
+
+	if grid.SizeX != resolution || grid.SizeY != resolution || grid.SizeZ != resolution || len(grid.Values) != resolution*resolution*resolution {
+		t.Fatalf("grid of %d x %d x %d with %d values, expected %d samples along each axis",
+			grid.SizeX, grid.SizeY, grid.SizeZ, len(grid.Values), resolution)
+	}
+
+	// The grid spans the root node, with x varying fastest in Values
+	var p Vector3
+	if grid.Position(0, 0, 0, &p); p != *bvh.rootNode.ExtentsMin {
+		t.Errorf("first sample at %v, expected %v", p, *bvh.rootNode.ExtentsMin)
+	}
+	extentsMax := bvh.rootNode.ExtentsMax
+	if grid.Position(resolution-1, resolution-1, resolution-1, &p); math.Abs(p.X-extentsMax.X) > 1e-12 ||
+		math.Abs(p.Y-extentsMax.Y) > 1e-12 || math.Abs(p.Z-extentsMax.Z) > 1e-12 {
+		t.Errorf("last sample at %v, expected %v", p, *extentsMax)
+	}
+
+	index := 0
+	for z := 0; z < resolution; z++ {
+		for y := 0; y < resolution; y++ {
+			for x := 0; x < resolution; x++ {
+				if grid.Index(x, y, z) != index {
+					t.Fatalf("Index(%d, %d, %d) = %d, expected %d", x, y, z, grid.Index(x, y, z), index)
+				}
+				if expected := bvh.SignedDistance(grid.Position(x, y, z, &p)); grid.Value(x, y, z) != expected {
+					t.Fatalf("Value(%d, %d, %d) = %v, expected %v", x, y, z, grid.Value(x, y, z), expected)
+				}
+				index++
+			}
+		}
+	}
+
+	for _, workers := range []int{0, 3, 8} {
+		if other := bvh.SignedDistanceGrid(resolution, workers); !reflect.DeepEqual(other, grid) {
+			t.Errorf("grid sampled by %d workers differs from the one sampled by 1", workers)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package bvhtree
+
+import "math"
+
+// RigidTransform is a rotation followed by a translation
+type RigidTransform struct {
+	Rotation    [9]float64 // Row-major 3x3 rotation matrix
+	Translation Vector3
+}
+
+// IdentityTransform returns a transform that leaves points unchanged
+func IdentityTransform() RigidTransform {
+	return RigidTransform{
+		Rotation: [9]float64{
+			1, 0, 0,
+			0, 1, 0,
+			0, 0, 1,
+		},
+	}
+}
+
+// NewRigidTransformFromAxisAngle creates a transform rotating by angle radians around the unit vector axis, then translating
+func NewRigidTransformFromAxisAngle(axis Point, angle float64, translation Point) RigidTransform {
+	c := math.Cos(angle)
+	s := math.Sin(angle)
+	t := 1 - c
+	x, y, z := axis.X, axis.Y, axis.Z
+
+	return RigidTransform{
+		Rotation: [9]float64{
+			t*x*x + c, t*x*y - s*z, t*x*z + s*y,
+			t*x*y + s*z, t*y*y + c, t*y*z - s*x,
+			t*x*z - s*y, t*y*z + s*x, t*z*z + c,
+		},
+		Translation: *translation,
+	}
+}
+
+// Apply stores the transformed point p in result and returns result
+func (transform *RigidTransform) Apply(p, result Point) Point {
+	r := &transform.Rotation
+	x := r[0]*p.X + r[1]*p.Y + r[2]*p.Z + transform.Translation.X
+	y := r[3]*p.X + r[4]*p.Y + r[5]*p.Z + transform.Translation.Y
+	z := r[6]*p.X + r[7]*p.Y + r[8]*p.Z + transform.Translation.Z
+	return result.Set(x, y, z)
+}
+
+// ApplyToBox stores the axis-aligned bounding box of the transformed box given by extentsMin and extentsMax
+// in resultMin and resultMax. The transformed center is surrounded by the absolute rotation applied to the half extents.
+func (transform *RigidTransform) ApplyToBox(extentsMin, extentsMax, resultMin, resultMax Point) {
+	center := Vector3{
+		X: (extentsMin.X + extentsMax.X) * 0.5,
+		Y: (extentsMin.Y + extentsMax.Y) * 0.5,
+		Z: (extentsMin.Z + extentsMax.Z) * 0.5,
+	}
+	halfX := (extentsMax.X - extentsMin.X) * 0.5
+	halfY := (extentsMax.Y - extentsMin.Y) * 0.5
+	halfZ := (extentsMax.Z - extentsMin.Z) * 0.5
+
+	transform.Apply(&center, &center)
+
+	r := &transform.Rotation
+	ex := math.Abs(r[0])*halfX + math.Abs(r[1])*halfY + math.Abs(r[2])*halfZ
+	ey := math.Abs(r[3])*halfX + math.Abs(r[4])*halfY + math.Abs(r[5])*halfZ
+	ez := math.Abs(r[6])*halfX + math.Abs(r[7])*halfY + math.Abs(r[8])*halfZ
+
+	resultMin.Set(center.X-ex, center.Y-ey, center.Z-ez)
+	resultMax.Set(center.X+ex, center.Y+ey, center.Z+ez)
+}
@@ -1,5 +1,7 @@
 package bvhtree
 
+import "math"
+
 // Vector3 is a 3D Vector class
 type Vector3 struct {
 	X, Y, Z float64
@@ -21,6 +23,18 @@ func NewPoint(arr ...float64) Point {
 	return p
 }
 
+// GetComponent returns the X, Y or Z component of the vector for index 0, 1 or 2
+func (v *Vector3) GetComponent(index int) float64 {
+	switch index {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
 // Copy copies the values from another vector
 func (v *Vector3) Copy(src *Vector3) *Vector3 {
 	v.X = src.X
@@ -96,6 +110,25 @@ func (v *Vector3) CrossVectors(a, b *Vector3) *Vector3 {
 	return v
 }
 
+// LengthSq returns the squared length of the vector
+func (v *Vector3) LengthSq() float64 {
+	return v.X*v.X + v.Y*v.Y + v.Z*v.Z
+}
+
+// Length returns the length of the vector
+func (v *Vector3) Length() float64 {
+	return math.Sqrt(v.LengthSq())
+}
+
+// Normalize scales the vector to unit length, a zero vector is left unchanged
+func (v *Vector3) Normalize() *Vector3 {
+	length := v.Length()
+	if length == 0 {
+		return v
+	}
+	return v.MultiplyScalar(1 / length)
+}
+
 // Clone creates a new vector with the same components as this vector
 func (v *Vector3) Clone() *Vector3 {
 	return &Vector3{v.X, v.Y, v.Z}
@@ -0,0 +1,110 @@
+package bvhtree
+
+import "math"
+
+// TriangleIntersection selects the ray-triangle intersection algorithm used by the ray queries of a BVH
+type TriangleIntersection int
+
+const (
+	// IntersectionMoller uses the edge tests of the Möller/Eberly style algorithm in IntersectRayTriangle
+	IntersectionMoller TriangleIntersection = iota
+	// IntersectionWatertight uses the watertight algorithm of Woop, Benthin and Wald, which never lets a ray
+	// pass between two triangles sharing an edge or a vertex
+	IntersectionWatertight
+)
+
+// SetTriangleIntersection selects the ray-triangle intersection algorithm used by all ray queries.
+// It must not be called while queries are running.
+func (bvh *BVH) SetTriangleIntersection(mode TriangleIntersection) {
+	bvh.triangleIntersection = mode
+}
+
+// intersectTriangle intersects a ray with a triangle using the BVH's intersection algorithm
+func (bvh *BVH) intersectTriangle(a, b, c, rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) (triangleHit, bool) {
+	if bvh.triangleIntersection == IntersectionWatertight {
+		return intersectRayTriangleWatertightHit(a, b, c, rayOrigin, rayDirection, tmin, tmax, backfaceCulling)
+	}
+	return intersectRayTriangleHit(a, b, c, rayOrigin, rayDirection, tmin, tmax, backfaceCulling)
+}
+
+// IntersectRayTriangleWatertight determines if a ray intersects with a triangle between the ray parameters tmin and tmax
+// using the watertight intersection algorithm
+func IntersectRayTriangleWatertight(a, b, c, rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) Point {
+	hit, ok := intersectRayTriangleWatertightHit(a, b, c, rayOrigin, rayDirection, tmin, tmax, backfaceCulling)
+	if !ok {
+		return nil
+	}
+	return pointOnRay(rayOrigin, rayDirection, hit.t)
+}
+
+// intersectRayTriangleWatertightHit implements "Watertight Ray/Triangle Intersection" (Woop et al. 2013).
+// The vertices are transformed into a space where the ray starts at the origin and points along +Z, which turns
+// the edge tests into 2D edge functions that are evaluated consistently for triangles sharing an edge.
+func intersectRayTriangleWatertightHit(a, b, c, rayOrigin, rayDirection Point, tmin, tmax float64, backfaceCulling bool) (triangleHit, bool) {
+	// Choose the dominant axis of the ray direction as Z and swap X and Y to preserve the winding direction
+	kz := 0
+	if math.Abs(rayDirection.Y) > math.Abs(rayDirection.GetComponent(kz)) {
+		kz = 1
+	}
+	if math.Abs(rayDirection.Z) > math.Abs(rayDirection.GetComponent(kz)) {
+		kz = 2
+	}
+	kx := (kz + 1) % 3
+	ky := (kx + 1) % 3
+	dirZ := rayDirection.GetComponent(kz)
+	if dirZ == 0 {
+		return triangleHit{}, false
+	}
+	if dirZ < 0 {
+		kx, ky = ky, kx
+	}
+
+	// Shear constants
+	sx := rayDirection.GetComponent(kx) / dirZ
+	sy := rayDirection.GetComponent(ky) / dirZ
+	sz := 1.0 / dirZ
+
+	// Vertices relative to the ray origin
+	ax, ay, az := a.GetComponent(kx)-rayOrigin.GetComponent(kx), a.GetComponent(ky)-rayOrigin.GetComponent(ky), a.GetComponent(kz)-rayOrigin.GetComponent(kz)
+	bx, by, bz := b.GetComponent(kx)-rayOrigin.GetComponent(kx), b.GetComponent(ky)-rayOrigin.GetComponent(ky), b.GetComponent(kz)-rayOrigin.GetComponent(kz)
+	cx, cy, cz := c.GetComponent(kx)-rayOrigin.GetComponent(kx), c.GetComponent(ky)-rayOrigin.GetComponent(ky), c.GetComponent(kz)-rayOrigin.GetComponent(kz)
+
+	// Shear and scale the vertices
+	ax, ay = ax-sx*az, ay-sy*az
+	bx, by = bx-sx*bz, by-sy*bz
+	cx, cy = cx-sx*cz, cy-sy*cz
+
+	// Scaled barycentric coordinates
+	u := cx*by - cy*bx
+	v := ax*cy - ay*cx
+	w := bx*ay - by*ax
+
+	// Edge tests, zero is accepted on both sides so that shared edges are never missed
+	if (u < 0 || v < 0 || w < 0) && (u > 0 || v > 0 || w > 0) {
+		return triangleHit{}, false
+	}
+
+	det := u + v + w
+	if det == 0 {
+		return triangleHit{}, false
+	}
+
+	// A positive determinant means the ray hits the side the normal of a, b, c points to
+	frontFace := det > 0
+	if backfaceCulling && !frontFace {
+		return triangleHit{}, false
+	}
+
+	// Scaled hit distance, t outside of [tmin, tmax], no intersection
+	t := (u*sz*az + v*sz*bz + w*sz*cz) / det
+	if t < tmin || t > tmax {
+		return triangleHit{}, false
+	}
+
+	return triangleHit{
+		t:         t,
+		u:         v / det,
+		v:         w / det,
+		frontFace: frontFace,
+	}, true
+}
@@ -0,0 +1,94 @@
+package bvhtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// sharedEdges returns the pairs of triangles of the vertex array that share an edge, together with that edge
+func sharedEdges(vertexArray []float64) (pairs [][2]int, edges [][2]Vector3) {
+	vertex := func(triIndex, v int) Vector3 {
+		return Vector3{vertexArray[triIndex*9+v*3], vertexArray[triIndex*9+v*3+1], vertexArray[triIndex*9+v*3+2]}
+	}
+
+	firstTriangle := map[[2]Vector3]int{}
+	for triIndex := 0; triIndex < len(vertexArray)/9; triIndex++ {
+		for v := 0; v < 3; v++ {
+			a, b := vertex(triIndex, v), vertex(triIndex, (v+1)%3)
+			key := [2]Vector3{a, b}
+			if a.X > b.X || (a.X == b.X && (a.Y > b.Y || (a.Y == b.Y && a.Z > b.Z))) {
+				key = [2]Vector3{b, a}
+			}
+
+			if other, ok := firstTriangle[key]; ok {
+				pairs = append(pairs, [2]int{other, triIndex})
+				edges = append(edges, key)
+			} else {
+				firstTriangle[key] = triIndex
+			}
+		}
+	}
+
+	return pairs, edges
+}
+
+func TestWatertightSharedEdges(t *testing.T) {
+	vertexArray := sphereMesh(24, 48, 10)
+	pairs, edges := sharedEdges(vertexArray)
+	if len(pairs) != len(vertexArray)/9*3/2 {
+		t.Fatalf("found %d shared edges in a closed mesh of %d triangles", len(pairs), len(vertexArray)/9)
+	}
+
+	bvh := newSAHBVH(vertexArray)
+	bvh.SetTriangleIntersection(IntersectionWatertight)
+
+	r := rand.New(rand.NewSource(1))
+	mollerMisses := 0
+
+	for i, pair := range pairs {
+		for j, s := range []float64{0.25, 0.5, r.Float64()} {
+			edge := edges[i]
+			target := NewPoint(edge[0].X+s*(edge[1].X-edge[0].X), edge[0].Y+s*(edge[1].Y-edge[0].Y), edge[0].Z+s*(edge[1].Z-edge[0].Z))
+
+			// Rays start near the center or outside behind the target, so that they cross the surface instead of grazing it
+			origin := NewPoint(r.Float64()*4-2, r.Float64()*4-2, r.Float64()*4-2)
+			if j != 1 {
+				origin.Add((&Vector3{}).Copy(target).MultiplyScalar(3))
+			}
+			direction := (&Vector3{}).SubVectors(target, origin)
+
+			if bvh.IntersectRayClosest(origin, direction, false) == nil {
+				t.Fatalf("watertight ray from %v to %v on a shared edge missed the closed mesh", *origin, *target)
+			}
+
+			watertightHits, mollerHits := 0, 0
+			for _, triIndex := range pair {
+				tri := bvh.Triangle(triIndex)
+				watertight, watertightOk := intersectRayTriangleWatertightHit(tri[0], tri[1], tri[2], origin, direction, 0, math.Inf(1), false)
+				moller, mollerOk := intersectRayTriangleHit(tri[0], tri[1], tri[2], origin, direction, 0, math.Inf(1), false)
+				if watertightOk {
+					watertightHits++
+				}
+				if mollerOk {
+					mollerHits++
+				}
+
+				// On the shared edge either triangle may claim the hit, so only hits of both algorithms are compared
+				if watertightOk && mollerOk && (watertight.frontFace != moller.frontFace ||
+					math.Abs(watertight.t-moller.t) > 1e-9 || math.Abs(watertight.u-moller.u) > 1e-9 || math.Abs(watertight.v-moller.v) > 1e-9) {
+					t.Fatalf("triangle %d: watertight hit %+v, Möller hit %+v", triIndex, watertight, moller)
+				}
+			}
+
+			if watertightHits == 0 {
+				t.Fatalf("watertight ray from %v to %v missed both triangles of the shared edge", *origin, *target)
+			}
+			if mollerHits == 0 {
+				mollerMisses++
+			}
+		}
+	}
+
+	t.Logf("Möller missed both triangles for %d of %d rays", mollerMisses, 3*len(pairs))
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"github.com/andrylavr/bvhtree"
 	"github.com/andrylavr/wasmo"
+	"math"
 	"syscall/js"
 )
 
@@ -48,12 +49,21 @@ func intersectRayJS(this js.Value, args []js.Value) interface{} {
 	rayDirection := jsToPoint(args[1])
 	backfaceCulling := args[2].Bool()
 
+	// Like THREE.Raycaster, near defaults to 0 and far to Infinity when left out or undefined
+	near, far := 0.0, math.Inf(1)
+	if len(args) > 3 && args[3].Type() == js.TypeNumber {
+		near = args[3].Float()
+	}
+	if len(args) > 4 && args[4].Type() == js.TypeNumber {
+		far = args[4].Float()
+	}
+
 	//fmt.Println("intersectRayJS:")
 	printPoint("rayOrigin", rayOrigin)
 	printPoint("rayDirection", rayDirection)
 	//fmt.Println("backfaceCulling", backfaceCulling)
 
-	intersectionResults := bvh.IntersectRay(rayOrigin, rayDirection, backfaceCulling)
+	intersectionResults := bvh.IntersectRayRange(rayOrigin, rayDirection, near, far, backfaceCulling)
 	//fmt.Println("intersectionResults len", len(intersectionResults))
 	for _, intersectionResult := range intersectionResults {
 		intersectionJS := Object.New()